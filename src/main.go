@@ -1,11 +1,15 @@
-//go:generate go run assets/generate.go
+//go:generate go run -modfile=assets/generate.mod assets/generate.go
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/indece-official/loadtest/src/model"
 	"github.com/indece-official/loadtest/src/report"
@@ -25,6 +29,7 @@ var (
 var flagVerbose = flag.Bool("v", false, "Verbose")
 var flagFile = flag.String("f", "", "Filename of test yaml")
 var flagReport = flag.String("r", "", "Filename of output report")
+var flagTimeout = flag.Duration("t", 0, "Global timeout for all tests (e.g. 10m)")
 
 func loadConfig() (*model.Config, error) {
 	if *flagFile == "" {
@@ -74,6 +79,23 @@ func main() {
 		return
 	}
 
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+
+		// Restore default behaviour, so a second signal kills the process
+		stop()
+	}()
+
+	if *flagTimeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, *flagTimeout)
+		defer cancel()
+	}
+
 	report := report.NewReport()
 	runStats := stats.NewRunStats()
 	vm := otto.New()
@@ -82,8 +104,8 @@ func main() {
 
 	runStats.SetStart()
 
-	err = config.Execute([]string{}, vm, runStats, report)
-	if err != nil {
+	err = config.Execute(ctx, []string{}, vm, runStats, report)
+	if err != nil && ctx.Err() == nil {
 		log.Fatalf("Error running tests: %s", err)
 
 		os.Exit(1)
@@ -93,7 +115,13 @@ func main() {
 
 	runStats.SetEnd()
 
-	log.Infof("Successfully finished tests")
+	if ctx.Err() != nil {
+		runStats.SetInterrupted()
+
+		log.Warnf("Tests were interrupted after %s: %s", runStats.TotalDuration.Round(time.Millisecond), ctx.Err())
+	} else {
+		log.Infof("Successfully finished tests")
+	}
 
 	runStats.Aggregate()
 
@@ -123,4 +151,8 @@ func main() {
 
 		log.Infof("Successfully generated report")
 	}
+
+	if runStats.Interrupted {
+		os.Exit(1)
+	}
 }
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/indece-official/loadtest/src/report"
@@ -32,9 +33,9 @@ func (l *Config) Validate() error {
 	return nil
 }
 
-func (l *Config) Execute(path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) error {
+func (l *Config) Execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) error {
 	for _, test := range l.Tests {
-		err := test.Execute(path, vm, runStats, report)
+		err := test.Execute(ctx, path, vm, runStats, report)
 		if err != nil {
 			return fmt.Errorf("load test %s failed: %s", test.Name, err)
 		}
@@ -0,0 +1,109 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gopkg.in/guregu/null.v4"
+)
+
+type ErrorPolicy string
+
+const (
+	ErrorPolicyContinue       ErrorPolicy = "continue"
+	ErrorPolicyAbortIteration ErrorPolicy = "abort_iteration"
+	ErrorPolicyAbortThread    ErrorPolicy = "abort_thread"
+	ErrorPolicyAbortTest      ErrorPolicy = "abort_test"
+	ErrorPolicyRetry          ErrorPolicy = "retry"
+)
+
+func (e ErrorPolicy) Validate() error {
+	switch e {
+	case "",
+		ErrorPolicyContinue,
+		ErrorPolicyAbortIteration,
+		ErrorPolicyAbortThread,
+		ErrorPolicyAbortTest,
+		ErrorPolicyRetry:
+		return nil
+	default:
+		return fmt.Errorf("unsupported error policy '%s'", e)
+	}
+}
+
+type StepRetry struct {
+	Count             int64       `yaml:"count"`
+	Backoff           null.String `yaml:"backoff"`
+	BackoffMultiplier null.Float  `yaml:"backoff_multiplier"`
+}
+
+func (s *StepRetry) Validate() error {
+	if s.Count <= 0 {
+		return fmt.Errorf("count must be greater 0")
+	}
+
+	if s.Backoff.Valid {
+		_, err := parseDurationOrNull("backoff", s.Backoff)
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.BackoffMultiplier.Valid && s.BackoffMultiplier.Float64 < 1 {
+		return fmt.Errorf("'backoff_multiplier' must be greater or equal 1")
+	}
+
+	return nil
+}
+
+// BackoffDuration returns the duration to wait before the given retry attempt (starting at 0)
+func (s *StepRetry) BackoffDuration(attempt int64) (time.Duration, error) {
+	if !s.Backoff.Valid {
+		return 0, nil
+	}
+
+	backoff, err := parseDurationOrNull("backoff", s.Backoff)
+	if err != nil {
+		return 0, err
+	}
+
+	if s.BackoffMultiplier.Valid {
+		for i := int64(0); i < attempt; i++ {
+			backoff = time.Duration(float64(backoff) * s.BackoffMultiplier.Float64)
+		}
+	}
+
+	return backoff, nil
+}
+
+// StepAbortError is returned by a step if its error policy requires
+// parent steps to stop the current iteration, thread or test
+type StepAbortError struct {
+	Policy ErrorPolicy
+	Err    error
+}
+
+func (s *StepAbortError) Error() string {
+	return s.Err.Error()
+}
+
+func (s *StepAbortError) Unwrap() error {
+	return s.Err
+}
+
+// isAbortError checks if err is a StepAbortError with one of the given policies
+func isAbortError(err error, policies ...ErrorPolicy) bool {
+	abortErr := &StepAbortError{}
+	if !errors.As(err, &abortErr) {
+		return false
+	}
+
+	for _, policy := range policies {
+		if abortErr.Policy == policy {
+			return true
+		}
+	}
+
+	return false
+}
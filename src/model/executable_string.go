@@ -1,6 +1,7 @@
 package model
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/robertkrimen/otto"
@@ -35,14 +36,19 @@ func (e *ExecutableStringOrNull) UnmarshalYAML(unmarshal func(interface{}) error
 	return nil
 }
 
-func (e *ExecutableStringOrNull) Execute(vm *otto.Otto) (otto.Value, error) {
+// Execute runs the script, it is interrupted once the context is cancelled
+func (e *ExecutableStringOrNull) Execute(ctx context.Context, vm *otto.Otto) (otto.Value, error) {
 	if !e.Valid {
 		return otto.NullValue(), nil
 	}
 
 	mutexVm.Lock()
 	defer mutexVm.Unlock()
-	val, err := vm.Run(e.String)
+	val, err := runScript(ctx, vm, e.String)
+	if err != nil && ctx.Err() != nil {
+		return otto.NullValue(), ctx.Err()
+	}
+
 	if err != nil {
 		return otto.NullValue(), fmt.Errorf("can't execute script: %s", err)
 	}
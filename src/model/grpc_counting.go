@@ -0,0 +1,64 @@
+package model
+
+import (
+	"context"
+	"sync/atomic"
+
+	grpcstats "google.golang.org/grpc/stats"
+)
+
+const contextKeyGrpcCallStats contextKey = "grpc_call_stats"
+
+// grpcCallStats counts the wire bytes and messages of one grpc call
+type grpcCallStats struct {
+	bytesSent        int64
+	bytesReceived    int64
+	messagesReceived int64
+}
+
+func (g *grpcCallStats) BytesSent() int64 {
+	return atomic.LoadInt64(&g.bytesSent)
+}
+
+func (g *grpcCallStats) BytesReceived() int64 {
+	return atomic.LoadInt64(&g.bytesReceived)
+}
+
+func (g *grpcCallStats) MessagesReceived() int64 {
+	return atomic.LoadInt64(&g.messagesReceived)
+}
+
+// grpcStatsHandler adds the payloads of a call to the grpcCallStats in its context
+type grpcStatsHandler struct{}
+
+func (g *grpcStatsHandler) TagRPC(ctx context.Context, info *grpcstats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (g *grpcStatsHandler) HandleRPC(ctx context.Context, rpcStats grpcstats.RPCStats) {
+	callStats, ok := ctx.Value(contextKeyGrpcCallStats).(*grpcCallStats)
+	if !ok {
+		return
+	}
+
+	switch typedStats := rpcStats.(type) {
+	case *grpcstats.OutPayload:
+		atomic.AddInt64(&callStats.bytesSent, int64(typedStats.WireLength))
+	case *grpcstats.InPayload:
+		atomic.AddInt64(&callStats.bytesReceived, int64(typedStats.WireLength))
+		atomic.AddInt64(&callStats.messagesReceived, 1)
+	}
+}
+
+func (g *grpcStatsHandler) TagConn(ctx context.Context, info *grpcstats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (g *grpcStatsHandler) HandleConn(ctx context.Context, connStats grpcstats.ConnStats) {
+}
+
+func contextWithGrpcCallStats(ctx context.Context, callStats *grpcCallStats) context.Context {
+	return context.WithValue(ctx, contextKeyGrpcCallStats, callStats)
+}
+
+var _ grpcstats.Handler = (*grpcStatsHandler)(nil)
@@ -0,0 +1,165 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/grpc"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// splitGrpcMethod splits 'package.Service/Method' or 'package.Service.Method'
+// into the full service and method name
+func splitGrpcMethod(method string) (string, string, error) {
+	method = strings.TrimPrefix(method, "/")
+
+	index := strings.LastIndex(method, "/")
+	if index < 0 {
+		index = strings.LastIndex(method, ".")
+	}
+
+	if index <= 0 || index == len(method)-1 {
+		return "", "", fmt.Errorf("method must have the format 'package.Service/Method', got '%s'", method)
+	}
+
+	return method[:index], method[index+1:], nil
+}
+
+func findGrpcMethod(files interface {
+	FindDescriptorByName(protoreflect.FullName) (protoreflect.Descriptor, error)
+}, method string) (protoreflect.MethodDescriptor, error) {
+	serviceName, methodName, err := splitGrpcMethod(method)
+	if err != nil {
+		return nil, err
+	}
+
+	descriptor, err := files.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return nil, fmt.Errorf("can't find service '%s': %s", serviceName, err)
+	}
+
+	serviceDescriptor, ok := descriptor.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("'%s' is not a service", serviceName)
+	}
+
+	methodDescriptor := serviceDescriptor.Methods().ByName(protoreflect.Name(methodName))
+	if methodDescriptor == nil {
+		return nil, fmt.Errorf("service '%s' has no method '%s'", serviceName, methodName)
+	}
+
+	return methodDescriptor, nil
+}
+
+// grpcMethodFromProtoFiles compiles the proto files and looks up the method
+func grpcMethodFromProtoFiles(ctx context.Context, protoFiles []string, importPaths []string, method string) (protoreflect.MethodDescriptor, error) {
+	compiler := &protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			ImportPaths: importPaths,
+		}),
+	}
+
+	files, err := compiler.Compile(ctx, protoFiles...)
+	if err != nil {
+		return nil, fmt.Errorf("can't compile proto files: %s", err)
+	}
+
+	return findGrpcMethod(files.AsResolver(), method)
+}
+
+// grpcMethodFromReflection loads the descriptors of the service via server reflection
+func grpcMethodFromReflection(ctx context.Context, conn *grpc.ClientConn, method string) (protoreflect.MethodDescriptor, error) {
+	serviceName, _, err := splitGrpcMethod(method)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can't start server reflection: %s", err)
+	}
+	defer stream.CloseSend()
+
+	fileProtos := map[string]*descriptorpb.FileDescriptorProto{}
+
+	// A server not returning a requested file would otherwise be asked for it forever
+	requestedFiles := map[string]bool{}
+
+	request := &reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{
+			FileContainingSymbol: serviceName,
+		},
+	}
+
+	// Request files until all dependencies are known
+	for request != nil {
+		err = stream.Send(request)
+		if err != nil {
+			return nil, fmt.Errorf("can't send server reflection request: %s", err)
+		}
+
+		response, err := stream.Recv()
+		if err != nil {
+			return nil, fmt.Errorf("can't receive server reflection response: %s", err)
+		}
+
+		if errorResponse := response.GetErrorResponse(); errorResponse != nil {
+			return nil, fmt.Errorf("server reflection failed: %s", errorResponse.GetErrorMessage())
+		}
+
+		for _, data := range response.GetFileDescriptorResponse().GetFileDescriptorProto() {
+			fileProto := &descriptorpb.FileDescriptorProto{}
+
+			err = proto.Unmarshal(data, fileProto)
+			if err != nil {
+				return nil, fmt.Errorf("can't decode file descriptor: %s", err)
+			}
+
+			fileProtos[fileProto.GetName()] = fileProto
+		}
+
+		request = nil
+
+		for _, fileProto := range fileProtos {
+			for _, dependency := range fileProto.GetDependency() {
+				if _, ok := fileProtos[dependency]; !ok {
+					if requestedFiles[dependency] {
+						return nil, fmt.Errorf("server reflection didn't return the file '%s'", dependency)
+					}
+
+					requestedFiles[dependency] = true
+
+					request = &reflectionpb.ServerReflectionRequest{
+						MessageRequest: &reflectionpb.ServerReflectionRequest_FileByFilename{
+							FileByFilename: dependency,
+						},
+					}
+
+					break
+				}
+			}
+
+			if request != nil {
+				break
+			}
+		}
+	}
+
+	fileSet := &descriptorpb.FileDescriptorSet{}
+	for _, fileProto := range fileProtos {
+		fileSet.File = append(fileSet.File, fileProto)
+	}
+
+	files, err := protodesc.NewFiles(fileSet)
+	if err != nil {
+		return nil, fmt.Errorf("can't build file descriptors: %s", err)
+	}
+
+	return findGrpcMethod(files, method)
+}
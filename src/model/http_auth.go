@@ -0,0 +1,148 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/robertkrimen/otto"
+	"gopkg.in/guregu/null.v4"
+)
+
+type HttpAuthBasic struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+type HttpAuthBearer struct {
+	Token    null.String `yaml:"token"`
+	Variable null.String `yaml:"variable"`
+}
+
+type HttpAuth struct {
+	Basic    *HttpAuthBasic    `yaml:"basic"`
+	Bearer   *HttpAuthBearer   `yaml:"bearer"`
+	OAuth2   *HttpAuthOAuth2   `yaml:"oauth2"`
+	Digest   *HttpAuthDigest   `yaml:"digest"`
+	AWSSigV4 *HttpAuthAWSSigV4 `yaml:"aws_sigv4"`
+}
+
+func (h *HttpAuth) Validate() error {
+	count := 0
+
+	for _, isSet := range []bool{
+		h.Basic != nil,
+		h.Bearer != nil,
+		h.OAuth2 != nil,
+		h.Digest != nil,
+		h.AWSSigV4 != nil,
+	} {
+		if isSet {
+			count++
+		}
+	}
+
+	if count != 1 {
+		return fmt.Errorf("auth must contain one child of 'basic' | 'bearer' | 'oauth2' | 'digest' | 'aws_sigv4'")
+	}
+
+	if h.Bearer != nil && h.Bearer.Token.Valid == h.Bearer.Variable.Valid {
+		return fmt.Errorf("bearer auth must contain one child of 'token' | 'variable'")
+	}
+
+	if h.OAuth2 != nil {
+		err := h.OAuth2.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid oauth2 auth: %s", err)
+		}
+	}
+
+	if h.AWSSigV4 != nil {
+		err := h.AWSSigV4.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid aws_sigv4 auth: %s", err)
+		}
+	}
+
+	return nil
+}
+
+func (h *HttpAuthBearer) token(vm *otto.Otto) (string, error) {
+	if h.Variable.Valid {
+		mutexVm.Lock()
+		defer mutexVm.Unlock()
+
+		val, err := vm.Get(h.Variable.String)
+		if err != nil {
+			return "", fmt.Errorf("can't get variable '%s': %s", h.Variable.String, err)
+		}
+
+		if !val.IsDefined() {
+			return "", fmt.Errorf("variable '%s' is not defined", h.Variable.String)
+		}
+
+		return val.String(), nil
+	}
+
+	token, err := interpolateString(vm, h.Token.String)
+	if err != nil {
+		return "", fmt.Errorf("error in 'token': %s", err)
+	}
+
+	return token, nil
+}
+
+// apply adds the credentials to the request, digest auth is handled in Do
+func (h *HttpAuth) apply(ctx context.Context, req *http.Request, vm *otto.Otto) error {
+	switch {
+	case h.Basic != nil:
+		username, err := interpolateString(vm, h.Basic.Username)
+		if err != nil {
+			return fmt.Errorf("error in 'username': %s", err)
+		}
+
+		password, err := interpolateString(vm, h.Basic.Password)
+		if err != nil {
+			return fmt.Errorf("error in 'password': %s", err)
+		}
+
+		req.SetBasicAuth(username, password)
+	case h.Bearer != nil:
+		token, err := h.Bearer.token(vm)
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
+	case h.OAuth2 != nil:
+		token, err := h.OAuth2.accessToken(ctx, vm)
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set("Authorization", token.authorizationHeader())
+	case h.AWSSigV4 != nil:
+		return h.AWSSigV4.sign(req, vm)
+	}
+
+	return nil
+}
+
+// httpSendFunc executes the authenticated request, it is called again
+// if a digest challenge had to be answered
+type httpSendFunc func(req *http.Request) (*http.Response, error)
+
+// Do authenticates the request and executes it with send, tokens and digest
+// challenges are requested with client before send is called
+func (h *HttpAuth) Do(ctx context.Context, client *http.Client, req *http.Request, vm *otto.Otto, send httpSendFunc) (*http.Response, error) {
+	if h.Digest != nil {
+		return h.Digest.do(ctx, client, req, vm, send)
+	}
+
+	err := h.apply(ctx, req, vm)
+	if err != nil {
+		return nil, fmt.Errorf("can't authenticate http request: %s", err)
+	}
+
+	return send(req)
+}
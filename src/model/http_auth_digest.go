@@ -0,0 +1,343 @@
+package model
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/robertkrimen/otto"
+)
+
+type HttpAuthDigest struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// digestChallenge is the last challenge of a server, cached per virtual user
+// so following requests can be authenticated without an additional round trip
+type digestChallenge struct {
+	Realm      string
+	Nonce      string
+	Opaque     string
+	Algorithm  string
+	QOP        string
+	NonceCount int
+}
+
+// parseDigestChallenge parses a 'WWW-Authenticate: Digest ...' header value
+func parseDigestChallenge(header string) (*digestChallenge, bool) {
+	if len(header) < 7 || !strings.EqualFold(header[:7], "digest ") {
+		return nil, false
+	}
+
+	params := map[string]string{}
+	rest := strings.TrimSpace(header[7:])
+
+	for rest != "" {
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 {
+			break
+		}
+
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = strings.TrimSpace(rest[eq+1:])
+
+		var value string
+
+		if strings.HasPrefix(rest, `"`) {
+			end := 1
+			for end < len(rest) && rest[end] != '"' {
+				if rest[end] == '\\' {
+					end++
+				}
+				end++
+			}
+
+			if end >= len(rest) {
+				return nil, false
+			}
+
+			value = unquoteDigestValue(rest[1:end])
+			rest = rest[end+1:]
+		} else {
+			end := strings.IndexByte(rest, ',')
+			if end < 0 {
+				end = len(rest)
+			}
+
+			value = strings.TrimSpace(rest[:end])
+			rest = rest[end:]
+		}
+
+		params[key] = value
+		rest = strings.TrimLeft(rest, ", ")
+	}
+
+	if params["nonce"] == "" {
+		return nil, false
+	}
+
+	challenge := &digestChallenge{
+		Realm:     params["realm"],
+		Nonce:     params["nonce"],
+		Opaque:    params["opaque"],
+		Algorithm: params["algorithm"],
+	}
+
+	// Only 'auth' is supported, 'auth-int' would require hashing the body
+	for _, qop := range strings.Split(params["qop"], ",") {
+		if strings.TrimSpace(qop) == "auth" {
+			challenge.QOP = "auth"
+		}
+	}
+
+	return challenge, true
+}
+
+// unquoteDigestValue removes the backslashes of escaped characters in a quoted value
+func unquoteDigestValue(value string) string {
+	builder := strings.Builder{}
+
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			i++
+		}
+
+		builder.WriteByte(value[i])
+	}
+
+	return builder.String()
+}
+
+func (d *digestChallenge) hash(data string) string {
+	var hasher hash.Hash
+
+	switch strings.TrimSuffix(strings.ToUpper(d.Algorithm), "-SESS") {
+	case "SHA-256":
+		hasher = sha256.New()
+	default:
+		hasher = md5.New()
+	}
+
+	io.WriteString(hasher, data)
+
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// response computes the digest of the credentials for the given nonce count and client nonce
+func (d *digestChallenge) response(method string, uri string, username string, password string, nonceCount string, cnonce string) string {
+	ha1 := d.hash(username + ":" + d.Realm + ":" + password)
+	if strings.HasSuffix(strings.ToUpper(d.Algorithm), "-SESS") {
+		ha1 = d.hash(ha1 + ":" + d.Nonce + ":" + cnonce)
+	}
+
+	ha2 := d.hash(method + ":" + uri)
+
+	if d.QOP != "" {
+		return d.hash(strings.Join([]string{ha1, d.Nonce, nonceCount, cnonce, d.QOP, ha2}, ":"))
+	}
+
+	return d.hash(ha1 + ":" + d.Nonce + ":" + ha2)
+}
+
+// authorizationHeader computes the response for the next request with this challenge
+func (d *digestChallenge) authorizationHeader(method string, uri string, username string, password string) (string, error) {
+	d.NonceCount++
+
+	cnonceBytes := make([]byte, 8)
+
+	_, err := rand.Read(cnonceBytes)
+	if err != nil {
+		return "", fmt.Errorf("can't generate cnonce: %s", err)
+	}
+
+	cnonce := hex.EncodeToString(cnonceBytes)
+	nonceCount := fmt.Sprintf("%08x", d.NonceCount)
+
+	response := d.response(method, uri, username, password, nonceCount, cnonce)
+
+	parts := []string{
+		fmt.Sprintf(`username="%s"`, username),
+		fmt.Sprintf(`realm="%s"`, d.Realm),
+		fmt.Sprintf(`nonce="%s"`, d.Nonce),
+		fmt.Sprintf(`uri="%s"`, uri),
+		fmt.Sprintf(`response="%s"`, response),
+	}
+
+	if d.Algorithm != "" {
+		parts = append(parts, fmt.Sprintf(`algorithm=%s`, d.Algorithm))
+	}
+
+	if d.QOP != "" {
+		parts = append(parts,
+			fmt.Sprintf(`qop=%s`, d.QOP),
+			fmt.Sprintf(`nc=%s`, nonceCount),
+			fmt.Sprintf(`cnonce="%s"`, cnonce),
+		)
+	}
+
+	if d.Opaque != "" {
+		parts = append(parts, fmt.Sprintf(`opaque="%s"`, d.Opaque))
+	}
+
+	return "Digest " + strings.Join(parts, ", "), nil
+}
+
+// cloneRequest returns a copy of the request with a fresh body for sending it again
+func cloneRequest(req *http.Request) (*http.Request, error) {
+	clonedReq := req.Clone(req.Context())
+
+	if req.Body == nil || req.Body == http.NoBody {
+		return clonedReq, nil
+	}
+
+	if req.GetBody == nil {
+		return nil, fmt.Errorf("request body can't be sent twice")
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("can't get request body: %s", err)
+	}
+
+	clonedReq.Body = body
+
+	return clonedReq, nil
+}
+
+func (h *HttpAuthDigest) authorize(req *http.Request, challenge *digestChallenge, username string, password string) error {
+	header, err := challenge.authorizationHeader(req.Method, req.URL.RequestURI(), username, password)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", header)
+
+	return nil
+}
+
+// challengeFromResponse returns the first digest challenge of a 401 response
+func challengeFromResponse(resp *http.Response) *digestChallenge {
+	if resp.StatusCode != http.StatusUnauthorized {
+		return nil
+	}
+
+	for _, header := range resp.Header.Values("WWW-Authenticate") {
+		challenge, ok := parseDigestChallenge(header)
+		if ok {
+			return challenge
+		}
+	}
+
+	return nil
+}
+
+// probe requests the challenge of the server with a copy of the request without body,
+// it returns nil if the server doesn't require digest auth
+func (h *HttpAuthDigest) probe(client *http.Client, req *http.Request) (*digestChallenge, error) {
+	probeReq := req.Clone(req.Context())
+	probeReq.Body = http.NoBody
+	probeReq.GetBody = nil
+	probeReq.ContentLength = 0
+
+	resp, err := client.Do(probeReq)
+	if err != nil {
+		return nil, fmt.Errorf("can't request digest challenge: %s", err)
+	}
+	defer resp.Body.Close()
+
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	return challengeFromResponse(resp), nil
+}
+
+// do answers the digest challenge of the server before the request is sent, the challenge
+// is cached per virtual user and only requested if there is none. If the server rejects
+// the nonce of a cached challenge, the request is sent again with the new challenge.
+func (h *HttpAuthDigest) do(ctx context.Context, client *http.Client, req *http.Request, vm *otto.Otto, send httpSendFunc) (*http.Response, error) {
+	username, err := interpolateString(vm, h.Username)
+	if err != nil {
+		return nil, fmt.Errorf("error in 'username': %s", err)
+	}
+
+	password, err := interpolateString(vm, h.Password)
+	if err != nil {
+		return nil, fmt.Errorf("error in 'password': %s", err)
+	}
+
+	virtualUser := virtualUserFromContext(ctx)
+	cacheKey := req.URL.Host + "\n" + username
+	cached := false
+
+	if virtualUser != nil {
+		err = virtualUser.withDigestChallenge(cacheKey, func(challenge *digestChallenge) error {
+			cached = true
+
+			return h.authorize(req, challenge, username, password)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !cached {
+		challenge, err := h.probe(client, req)
+		if err != nil {
+			return nil, err
+		}
+
+		if challenge != nil {
+			err = h.authorize(req, challenge, username, password)
+			if err != nil {
+				return nil, err
+			}
+
+			if virtualUser != nil {
+				virtualUser.setDigestChallenge(cacheKey, challenge)
+			}
+		}
+
+		return send(req)
+	}
+
+	// A copy is kept for answering a new challenge if the body can be sent twice
+	var retryReq *http.Request
+	if req.GetBody != nil || req.Body == nil || req.Body == http.NoBody {
+		retryReq, err = cloneRequest(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := send(req)
+	if err != nil || retryReq == nil {
+		return resp, err
+	}
+
+	challenge := challengeFromResponse(resp)
+	if challenge == nil {
+		return resp, nil
+	}
+
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 1<<20))
+	resp.Body.Close()
+
+	err = h.authorize(retryReq, challenge, username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	if virtualUser != nil {
+		virtualUser.setDigestChallenge(cacheKey, challenge)
+	}
+
+	return send(retryReq)
+}
@@ -0,0 +1,125 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestParseDigestChallenge(t *testing.T) {
+	tests := []struct {
+		header   string
+		ok       bool
+		expected digestChallenge
+	}{
+		{
+			header: `Digest realm="http-auth@example.org", qop="auth, auth-int", algorithm=SHA-256, nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`,
+			ok:     true,
+			expected: digestChallenge{
+				Realm:     "http-auth@example.org",
+				Nonce:     "7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v",
+				Opaque:    "FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS",
+				Algorithm: "SHA-256",
+				QOP:       "auth",
+			},
+		},
+		{
+			header: `digest realm="a, b = c", nonce="n,1",qop="auth-int,auth"`,
+			ok:     true,
+			expected: digestChallenge{
+				Realm: "a, b = c",
+				Nonce: "n,1",
+				QOP:   "auth",
+			},
+		},
+		{
+			header: `Digest realm="say \"hi\", \\o/", nonce=abc, qop="auth-int"`,
+			ok:     true,
+			expected: digestChallenge{
+				Realm: `say "hi", \o/`,
+				Nonce: "abc",
+			},
+		},
+		{
+			header: `Digest realm="no nonce"`,
+			ok:     false,
+		},
+		{
+			header: `Digest realm="a", nonce="unterminated`,
+			ok:     false,
+		},
+		{
+			header: `Basic realm="basic"`,
+			ok:     false,
+		},
+	}
+
+	for _, test := range tests {
+		challenge, ok := parseDigestChallenge(test.header)
+		if ok != test.ok {
+			t.Errorf("parseDigestChallenge(%q) ok = %v, expected %v", test.header, ok, test.ok)
+
+			continue
+		}
+
+		if !ok {
+			continue
+		}
+
+		if *challenge != test.expected {
+			t.Errorf("parseDigestChallenge(%q) = %+v, expected %+v", test.header, *challenge, test.expected)
+		}
+	}
+}
+
+// Examples of RFC 2617 section 3.5 and RFC 7616 section 3.9.1
+func TestDigestChallengeResponse(t *testing.T) {
+	tests := []struct {
+		name      string
+		challenge digestChallenge
+		password  string
+		cnonce    string
+		expected  string
+	}{
+		{
+			name: "rfc2617",
+			challenge: digestChallenge{
+				Realm: "testrealm@host.com",
+				Nonce: "dcd98b7102dd2f0e8b11d0f600bfb0c093",
+				QOP:   "auth",
+			},
+			password: "Circle Of Life",
+			cnonce:   "0a4f113b",
+			expected: "6629fae49393a05397450978507c4ef1",
+		},
+		{
+			name: "rfc7616-md5",
+			challenge: digestChallenge{
+				Realm:     "http-auth@example.org",
+				Nonce:     "7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v",
+				Algorithm: "MD5",
+				QOP:       "auth",
+			},
+			password: "Circle of Life",
+			cnonce:   "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ",
+			expected: "8ca523f5e9506fed4657c9700eebdbec",
+		},
+		{
+			name: "rfc7616-sha256",
+			challenge: digestChallenge{
+				Realm:     "http-auth@example.org",
+				Nonce:     "7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v",
+				Algorithm: "SHA-256",
+				QOP:       "auth",
+			},
+			password: "Circle of Life",
+			cnonce:   "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ",
+			expected: "753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1",
+		},
+	}
+
+	for _, test := range tests {
+		response := test.challenge.response("GET", "/dir/index.html", "Mufasa", test.password, "00000001", test.cnonce)
+		if response != test.expected {
+			t.Errorf("%s: response = %s, expected %s", test.name, response, test.expected)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/robertkrimen/otto"
+	"gopkg.in/guregu/null.v4"
+)
+
+type OAuth2GrantType string
+
+const (
+	OAuth2GrantTypeClientCredentials OAuth2GrantType = "client_credentials"
+	OAuth2GrantTypePassword          OAuth2GrantType = "password"
+)
+
+type OAuth2ClientAuth string
+
+const (
+	OAuth2ClientAuthHeader OAuth2ClientAuth = "header"
+	OAuth2ClientAuthBody   OAuth2ClientAuth = "body"
+)
+
+// oauth2ExpiryMargin is subtracted from the token lifetime to refresh tokens before they expire
+const oauth2ExpiryMargin = 10 * time.Second
+
+type HttpAuthOAuth2 struct {
+	GrantType    OAuth2GrantType  `yaml:"grant_type"`
+	TokenURL     string           `yaml:"token_url"`
+	ClientID     string           `yaml:"client_id"`
+	ClientSecret null.String      `yaml:"client_secret"`
+	ClientAuth   OAuth2ClientAuth `yaml:"client_auth"`
+	Username     null.String      `yaml:"username"`
+	Password     null.String      `yaml:"password"`
+	Scope        null.String      `yaml:"scope"`
+}
+
+// oauth2Token is an access token cached per virtual user
+type oauth2Token struct {
+	AccessToken  string
+	TokenType    string
+	RefreshToken string
+	Expiry       time.Time
+}
+
+type oauth2TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+func (o *oauth2Token) valid() bool {
+	return o.Expiry.IsZero() || time.Now().Before(o.Expiry)
+}
+
+func (o *oauth2Token) authorizationHeader() string {
+	if o.TokenType == "" || strings.EqualFold(o.TokenType, "bearer") {
+		return "Bearer " + o.AccessToken
+	}
+
+	return o.TokenType + " " + o.AccessToken
+}
+
+func (h *HttpAuthOAuth2) Validate() error {
+	switch h.GrantType {
+	case OAuth2GrantTypeClientCredentials:
+	case OAuth2GrantTypePassword:
+		if !h.Username.Valid || !h.Password.Valid {
+			return fmt.Errorf("grant type 'password' requires 'username' and 'password'")
+		}
+	default:
+		return fmt.Errorf("unsupported grant_type '%s'", h.GrantType)
+	}
+
+	switch h.ClientAuth {
+	case "", OAuth2ClientAuthHeader, OAuth2ClientAuthBody:
+	default:
+		return fmt.Errorf("unsupported client_auth '%s'", h.ClientAuth)
+	}
+
+	if h.TokenURL == "" {
+		return fmt.Errorf("missing 'token_url'")
+	}
+
+	if h.ClientID == "" {
+		return fmt.Errorf("missing 'client_id'")
+	}
+
+	return nil
+}
+
+// interpolatedValues returns all configured values with their placeholders resolved
+func (h *HttpAuthOAuth2) interpolatedValues(vm *otto.Otto) (map[string]string, error) {
+	values := map[string]string{}
+
+	for name, value := range map[string]null.String{
+		"token_url":     null.StringFrom(h.TokenURL),
+		"client_id":     null.StringFrom(h.ClientID),
+		"client_secret": h.ClientSecret,
+		"username":      h.Username,
+		"password":      h.Password,
+		"scope":         h.Scope,
+	} {
+		if !value.Valid {
+			continue
+		}
+
+		interpolatedValue, err := interpolateString(vm, value.String)
+		if err != nil {
+			return nil, fmt.Errorf("error in '%s': %s", name, err)
+		}
+
+		values[name] = interpolatedValue
+	}
+
+	return values, nil
+}
+
+func (h *HttpAuthOAuth2) requestToken(ctx context.Context, client *http.Client, values map[string]string, form url.Values) (*oauth2Token, error) {
+	if h.ClientAuth == OAuth2ClientAuthBody {
+		form.Set("client_id", values["client_id"])
+
+		if clientSecret, ok := values["client_secret"]; ok {
+			form.Set("client_secret", clientSecret)
+		}
+	}
+
+	if scope, ok := values["scope"]; ok {
+		form.Set("scope", scope)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, values["token_url"], strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("can't create token request: %s", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	if h.ClientAuth != OAuth2ClientAuthBody {
+		req.SetBasicAuth(url.QueryEscape(values["client_id"]), url.QueryEscape(values["client_secret"]))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("can't execute token request: %s", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("can't read token response: %s", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("token request failed with status '%s': %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	tokenResponse := &oauth2TokenResponse{}
+
+	err = json.Unmarshal(body, tokenResponse)
+	if err != nil {
+		return nil, fmt.Errorf("can't decode token response: %s", err)
+	}
+
+	if tokenResponse.AccessToken == "" {
+		return nil, fmt.Errorf("token response contains no access_token")
+	}
+
+	token := &oauth2Token{
+		AccessToken:  tokenResponse.AccessToken,
+		TokenType:    tokenResponse.TokenType,
+		RefreshToken: tokenResponse.RefreshToken,
+	}
+
+	if tokenResponse.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(tokenResponse.ExpiresIn)*time.Second - oauth2ExpiryMargin)
+	}
+
+	return token, nil
+}
+
+// accessToken returns the cached token of the virtual user or requests a new one,
+// expired tokens are refreshed using the refresh token if the server issued one
+func (h *HttpAuthOAuth2) accessToken(ctx context.Context, vm *otto.Otto) (*oauth2Token, error) {
+	values, err := h.interpolatedValues(vm)
+	if err != nil {
+		return nil, err
+	}
+
+	client := http.DefaultClient
+	cacheKey := strings.Join([]string{
+		string(h.GrantType),
+		values["token_url"],
+		values["client_id"],
+		values["username"],
+		values["scope"],
+	}, "\n")
+
+	virtualUser := virtualUserFromContext(ctx)
+	if virtualUser != nil {
+		client = virtualUser.HttpClient()
+
+		token := virtualUser.oauth2Token(cacheKey)
+		if token != nil && token.valid() {
+			return token, nil
+		}
+
+		if token != nil && token.RefreshToken != "" {
+			form := url.Values{}
+			form.Set("grant_type", "refresh_token")
+			form.Set("refresh_token", token.RefreshToken)
+
+			refreshedToken, err := h.requestToken(ctx, client, values, form)
+			if err == nil {
+				if refreshedToken.RefreshToken == "" {
+					refreshedToken.RefreshToken = token.RefreshToken
+				}
+
+				virtualUser.setOAuth2Token(cacheKey, refreshedToken)
+
+				return refreshedToken, nil
+			}
+
+			// Fall back to a new grant if the refresh token was rejected
+		}
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", string(h.GrantType))
+
+	if h.GrantType == OAuth2GrantTypePassword {
+		form.Set("username", values["username"])
+		form.Set("password", values["password"])
+	}
+
+	token, err := h.requestToken(ctx, client, values, form)
+	if err != nil {
+		return nil, fmt.Errorf("can't get oauth2 token: %s", err)
+	}
+
+	if virtualUser != nil {
+		virtualUser.setOAuth2Token(cacheKey, token)
+	}
+
+	return token, nil
+}
@@ -0,0 +1,255 @@
+package model
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robertkrimen/otto"
+	"gopkg.in/guregu/null.v4"
+)
+
+const (
+	awsSigV4Algorithm       = "AWS4-HMAC-SHA256"
+	awsSigV4UnsignedPayload = "UNSIGNED-PAYLOAD"
+)
+
+type HttpAuthAWSSigV4 struct {
+	AccessKeyID     string      `yaml:"access_key_id"`
+	SecretAccessKey string      `yaml:"secret_access_key"`
+	SessionToken    null.String `yaml:"session_token"`
+	Region          string      `yaml:"region"`
+	Service         string      `yaml:"service"`
+}
+
+func (h *HttpAuthAWSSigV4) Validate() error {
+	if h.AccessKeyID == "" {
+		return fmt.Errorf("missing 'access_key_id'")
+	}
+
+	if h.SecretAccessKey == "" {
+		return fmt.Errorf("missing 'secret_access_key'")
+	}
+
+	if h.Region == "" {
+		return fmt.Errorf("missing 'region'")
+	}
+
+	if h.Service == "" {
+		return fmt.Errorf("missing 'service'")
+	}
+
+	return nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// awsURIEncode encodes a string as required by aws, only unreserved characters are kept
+func awsURIEncode(value string, encodeSlash bool) string {
+	builder := strings.Builder{}
+
+	for _, b := range []byte(value) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '_', b == '.', b == '~':
+			builder.WriteByte(b)
+		case b == '/' && !encodeSlash:
+			builder.WriteByte(b)
+		default:
+			fmt.Fprintf(&builder, "%%%02X", b)
+		}
+	}
+
+	return builder.String()
+}
+
+func awsCanonicalQuery(query url.Values) string {
+	params := []string{}
+
+	for name, values := range query {
+		for _, value := range values {
+			params = append(params, awsURIEncode(name, true)+"="+awsURIEncode(value, true))
+		}
+	}
+
+	sort.Strings(params)
+
+	return strings.Join(params, "&")
+}
+
+// payloadHash returns the hex encoded sha256 of the request body,
+// bodies which can't be read twice are sent unsigned
+func (h *HttpAuthAWSSigV4) payloadHash(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return sha256Hex(nil), nil
+	}
+
+	if req.GetBody == nil {
+		return awsSigV4UnsignedPayload, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return "", fmt.Errorf("can't get request body: %s", err)
+	}
+	defer body.Close()
+
+	hasher := sha256.New()
+
+	_, err = io.Copy(hasher, body)
+	if err != nil {
+		return "", fmt.Errorf("can't hash request body: %s", err)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// sign adds the aws signature version 4 to the request
+func (h *HttpAuthAWSSigV4) sign(req *http.Request, vm *otto.Otto) error {
+	values := map[string]string{}
+
+	for name, value := range map[string]null.String{
+		"access_key_id":     null.StringFrom(h.AccessKeyID),
+		"secret_access_key": null.StringFrom(h.SecretAccessKey),
+		"session_token":     h.SessionToken,
+		"region":            null.StringFrom(h.Region),
+		"service":           null.StringFrom(h.Service),
+	} {
+		if !value.Valid {
+			continue
+		}
+
+		interpolatedValue, err := interpolateString(vm, value.String)
+		if err != nil {
+			return fmt.Errorf("error in '%s': %s", name, err)
+		}
+
+		values[name] = interpolatedValue
+	}
+
+	return h.signAt(req, values, time.Now())
+}
+
+// signAt signs the request with the interpolated values at the given time
+func (h *HttpAuthAWSSigV4) signAt(req *http.Request, values map[string]string, now time.Time) error {
+	payloadHash, err := h.payloadHash(req)
+	if err != nil {
+		return err
+	}
+
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	// Only S3 requires the payload hash as header
+	if values["service"] == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	if sessionToken, ok := values["session_token"]; ok {
+		req.Header.Set("X-Amz-Security-Token", sessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	// The host and all x-amz-* headers are signed as well as the content type
+	headers := map[string]string{
+		"host": host,
+	}
+
+	for name, headerValues := range req.Header {
+		lowerName := strings.ToLower(name)
+
+		if strings.HasPrefix(lowerName, "x-amz-") || lowerName == "content-type" {
+			trimmedValues := []string{}
+			for _, value := range headerValues {
+				trimmedValues = append(trimmedValues, strings.Join(strings.Fields(value), " "))
+			}
+
+			headers[lowerName] = strings.Join(trimmedValues, ",")
+		}
+	}
+
+	headerNames := []string{}
+	for name := range headers {
+		headerNames = append(headerNames, name)
+	}
+
+	sort.Strings(headerNames)
+
+	canonicalHeaders := strings.Builder{}
+	for _, name := range headerNames {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+
+	signedHeaders := strings.Join(headerNames, ";")
+
+	canonicalURI := req.URL.EscapedPath()
+	if canonicalURI == "" {
+		canonicalURI = "/"
+	}
+
+	// S3 expects the path to be encoded only once
+	if values["service"] != "s3" {
+		canonicalURI = awsURIEncode(canonicalURI, false)
+	}
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI,
+		awsCanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{date, values["region"], values["service"], "aws4_request"}, "/")
+
+	stringToSign := strings.Join([]string{
+		awsSigV4Algorithm,
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := hmacSHA256([]byte("AWS4"+values["secret_access_key"]), date)
+	signingKey = hmacSHA256(signingKey, values["region"])
+	signingKey = hmacSHA256(signingKey, values["service"])
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		awsSigV4Algorithm,
+		values["access_key_id"],
+		scope,
+		signedHeaders,
+		signature,
+	))
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package model
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Vectors of the aws signature version 4 test suite
+func TestHttpAuthAWSSigV4Sign(t *testing.T) {
+	values := map[string]string{
+		"access_key_id":     "AKIDEXAMPLE",
+		"secret_access_key": "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		"region":            "us-east-1",
+		"service":           "service",
+	}
+
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		method        string
+		url           string
+		contentType   string
+		body          string
+		signedHeaders string
+		signature     string
+	}{
+		{
+			name:          "get-vanilla",
+			method:        http.MethodGet,
+			url:           "https://example.amazonaws.com/",
+			signedHeaders: "host;x-amz-date",
+			signature:     "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name:          "get-vanilla-query-order-key-case",
+			method:        http.MethodGet,
+			url:           "https://example.amazonaws.com/?Param2=value2&Param1=value1",
+			signedHeaders: "host;x-amz-date",
+			signature:     "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+		{
+			name:          "post-vanilla",
+			method:        http.MethodPost,
+			url:           "https://example.amazonaws.com/",
+			signedHeaders: "host;x-amz-date",
+			signature:     "5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b",
+		},
+		{
+			name:          "post-x-www-form-urlencoded",
+			method:        http.MethodPost,
+			url:           "https://example.amazonaws.com/",
+			contentType:   "application/x-www-form-urlencoded",
+			body:          "Param1=value1",
+			signedHeaders: "content-type;host;x-amz-date",
+			signature:     "ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a",
+		},
+	}
+
+	for _, test := range tests {
+		var req *http.Request
+		var err error
+
+		if test.body != "" {
+			req, err = http.NewRequest(test.method, test.url, strings.NewReader(test.body))
+		} else {
+			req, err = http.NewRequest(test.method, test.url, nil)
+		}
+		if err != nil {
+			t.Fatalf("%s: can't create request: %s", test.name, err)
+		}
+
+		if test.contentType != "" {
+			req.Header.Set("Content-Type", test.contentType)
+		}
+
+		auth := &HttpAuthAWSSigV4{}
+
+		err = auth.signAt(req, values, now)
+		if err != nil {
+			t.Errorf("%s: signAt returned error: %s", test.name, err)
+
+			continue
+		}
+
+		expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+			"SignedHeaders=" + test.signedHeaders + ", Signature=" + test.signature
+
+		if req.Header.Get("Authorization") != expected {
+			t.Errorf("%s: Authorization = %q, expected %q", test.name, req.Header.Get("Authorization"), expected)
+		}
+
+		if req.Header.Get("X-Amz-Date") != "20150830T123600Z" {
+			t.Errorf("%s: unexpected X-Amz-Date %q", test.name, req.Header.Get("X-Amz-Date"))
+		}
+	}
+}
+
+func TestHttpAuthAWSSigV4SignS3(t *testing.T) {
+	req, err := http.NewRequest(http.MethodGet, "https://examplebucket.s3.amazonaws.com/test.txt", nil)
+	if err != nil {
+		t.Fatalf("can't create request: %s", err)
+	}
+
+	auth := &HttpAuthAWSSigV4{}
+
+	err = auth.signAt(req, map[string]string{
+		"access_key_id":     "AKIDEXAMPLE",
+		"secret_access_key": "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		"region":            "us-east-1",
+		"service":           "s3",
+	}, time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("signAt returned error: %s", err)
+	}
+
+	emptyHash := "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	if req.Header.Get("X-Amz-Content-Sha256") != emptyHash {
+		t.Errorf("unexpected X-Amz-Content-Sha256 %q", req.Header.Get("X-Amz-Content-Sha256"))
+	}
+
+	if !strings.Contains(req.Header.Get("Authorization"), "SignedHeaders=host;x-amz-content-sha256;x-amz-date,") {
+		t.Errorf("payload hash must be signed: %q", req.Header.Get("Authorization"))
+	}
+}
@@ -0,0 +1,294 @@
+package model
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/robertkrimen/otto"
+	"gopkg.in/guregu/null.v4"
+)
+
+type HttpMultipartField struct {
+	Name  string      `yaml:"name"`
+	Value null.String `yaml:"value"`
+}
+
+type HttpMultipartFile struct {
+	Name        string      `yaml:"name"`
+	File        string      `yaml:"file"`
+	Filename    null.String `yaml:"filename"`
+	ContentType null.String `yaml:"content_type"`
+}
+
+type HttpMultipartBody struct {
+	Fields []HttpMultipartField `yaml:"fields"`
+	Files  []HttpMultipartFile  `yaml:"files"`
+}
+
+type HttpBody struct {
+	Value       null.String            `yaml:"value"`
+	Expr        ExecutableStringOrNull `yaml:"expr"`
+	Form        map[string]string      `yaml:"form"`
+	Multipart   *HttpMultipartBody     `yaml:"multipart"`
+	JSON        interface{}            `yaml:"json"`
+	File        null.String            `yaml:"file"`
+	Compression HttpCompression        `yaml:"compression"`
+}
+
+// httpRequestBody is the built body of a http request
+type httpRequestBody struct {
+	Reader          io.Reader
+	ContentLength   int64
+	ContentType     string
+	ContentEncoding string
+	// GetBody returns a new reader for sending the body again,
+	// it's set by the http request itself for buffered bodies
+	GetBody func() (io.ReadCloser, error)
+}
+
+// close closes the reader of a body that isn't sent, sent bodies are closed by the http client
+func (h *httpRequestBody) close() {
+	if closer, ok := h.Reader.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
+func (h *HttpBody) Validate() error {
+	count := 0
+
+	for _, isSet := range []bool{
+		h.Value.Valid,
+		h.Expr.Valid,
+		h.Form != nil,
+		h.Multipart != nil,
+		h.JSON != nil,
+		h.File.Valid,
+	} {
+		if isSet {
+			count++
+		}
+	}
+
+	if count > 1 {
+		return fmt.Errorf("request body must contain only one child of 'value' | 'expr' | 'form' | 'multipart' | 'json' | 'file'")
+	}
+
+	err := h.Compression.Validate()
+	if err != nil {
+		return err
+	}
+
+	if h.Multipart != nil {
+		for i, field := range h.Multipart.Fields {
+			if field.Name == "" {
+				return fmt.Errorf("multipart field %d must have a name", i+1)
+			}
+		}
+
+		for i, file := range h.Multipart.Files {
+			if file.Name == "" {
+				return fmt.Errorf("multipart file %d must have a name", i+1)
+			}
+
+			if file.File == "" {
+				return fmt.Errorf("multipart file '%s' must have a 'file'", file.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (h *HttpBody) buildForm(vm *otto.Otto) (*httpRequestBody, error) {
+	values := url.Values{}
+
+	for name, value := range h.Form {
+		interpolatedValue, err := interpolateString(vm, value)
+		if err != nil {
+			return nil, fmt.Errorf("error in form field '%s': %s", name, err)
+		}
+
+		values.Set(name, interpolatedValue)
+	}
+
+	data := values.Encode()
+
+	return &httpRequestBody{
+		Reader:        bytes.NewBufferString(data),
+		ContentLength: int64(len(data)),
+		ContentType:   "application/x-www-form-urlencoded",
+	}, nil
+}
+
+func (h *HttpBody) buildMultipart(vm *otto.Otto) (*httpRequestBody, error) {
+	buffer := &bytes.Buffer{}
+	writer := multipart.NewWriter(buffer)
+
+	for _, field := range h.Multipart.Fields {
+		value, err := interpolateString(vm, field.Value.String)
+		if err != nil {
+			return nil, fmt.Errorf("error in multipart field '%s': %s", field.Name, err)
+		}
+
+		err = writer.WriteField(field.Name, value)
+		if err != nil {
+			return nil, fmt.Errorf("can't write multipart field '%s': %s", field.Name, err)
+		}
+	}
+
+	for _, file := range h.Multipart.Files {
+		filename := filepath.Base(file.File)
+		if file.Filename.Valid {
+			filename = file.Filename.String
+		}
+
+		contentType := "application/octet-stream"
+		if file.ContentType.Valid {
+			contentType = file.ContentType.String
+		}
+
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, file.Name, filename))
+		header.Set("Content-Type", contentType)
+
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, fmt.Errorf("can't create multipart file '%s': %s", file.Name, err)
+		}
+
+		err = copyFile(part, file.File)
+		if err != nil {
+			return nil, fmt.Errorf("can't write multipart file '%s': %s", file.Name, err)
+		}
+	}
+
+	err := writer.Close()
+	if err != nil {
+		return nil, fmt.Errorf("can't finish multipart body: %s", err)
+	}
+
+	return &httpRequestBody{
+		Reader:        buffer,
+		ContentLength: int64(buffer.Len()),
+		ContentType:   writer.FormDataContentType(),
+	}, nil
+}
+
+func (h *HttpBody) buildJSON(vm *otto.Otto) (*httpRequestBody, error) {
+	value, err := interpolateValue(vm, h.JSON)
+	if err != nil {
+		return nil, fmt.Errorf("error in 'json': %s", err)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("can't encode 'json': %s", err)
+	}
+
+	return &httpRequestBody{
+		Reader:        bytes.NewBuffer(data),
+		ContentLength: int64(len(data)),
+		ContentType:   "application/json",
+	}, nil
+}
+
+func (h *HttpBody) buildFile() (*httpRequestBody, error) {
+	file, err := os.Open(h.File.String)
+	if err != nil {
+		return nil, fmt.Errorf("can't open 'file': %s", err)
+	}
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		file.Close()
+
+		return nil, fmt.Errorf("can't stat 'file': %s", err)
+	}
+
+	// The file is streamed and closed by the http client, it's opened again
+	// if the request has to be repeated (e.g. for digest auth)
+	return &httpRequestBody{
+		Reader:        file,
+		ContentLength: fileInfo.Size(),
+		ContentType:   "application/octet-stream",
+		GetBody: func() (io.ReadCloser, error) {
+			return os.Open(h.File.String)
+		},
+	}, nil
+}
+
+// Build creates the request body, the content type is empty for literal bodies
+func (h *HttpBody) Build(ctx context.Context, vm *otto.Otto) (*httpRequestBody, error) {
+	body, err := h.build(ctx, vm)
+	if err != nil {
+		return nil, err
+	}
+
+	if h.Compression == "" {
+		return body, nil
+	}
+
+	return h.Compression.compress(body)
+}
+
+func (h *HttpBody) build(ctx context.Context, vm *otto.Otto) (*httpRequestBody, error) {
+	switch {
+	case h.Value.Valid:
+		value, err := interpolateString(vm, h.Value.String)
+		if err != nil {
+			return nil, fmt.Errorf("error in 'value' of 'request_body': %s", err)
+		}
+
+		return &httpRequestBody{
+			Reader:        bytes.NewBufferString(value),
+			ContentLength: int64(len(value)),
+		}, nil
+	case h.Expr.Valid:
+		val, err := h.Expr.Execute(ctx, vm)
+		if err != nil {
+			return nil, fmt.Errorf("error executing 'expr' for 'request_body': %s", err)
+		}
+
+		strVal, err := val.ToString()
+		if err != nil {
+			return nil, fmt.Errorf("'expr' for 'request_body' must return a string: %s", err)
+		}
+
+		return &httpRequestBody{
+			Reader:        bytes.NewBufferString(strVal),
+			ContentLength: int64(len(strVal)),
+		}, nil
+	case h.Form != nil:
+		return h.buildForm(vm)
+	case h.Multipart != nil:
+		return h.buildMultipart(vm)
+	case h.JSON != nil:
+		return h.buildJSON(vm)
+	case h.File.Valid:
+		return h.buildFile()
+	}
+
+	return &httpRequestBody{
+		Reader: bytes.NewBufferString(""),
+	}, nil
+}
+
+func copyFile(writer io.Writer, filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(writer, file)
+
+	return err
+}
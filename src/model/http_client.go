@@ -0,0 +1,266 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/robertkrimen/otto"
+	"gopkg.in/guregu/null.v4"
+)
+
+const defaultMaxIdleConnsPerHost = 100
+const defaultMaxRedirects = 10
+
+type HttpProtocol string
+
+const (
+	// HttpProtocolHTTP1 only uses HTTP/1.1
+	HttpProtocolHTTP1 HttpProtocol = "http1"
+	// HttpProtocolH2 negotiates HTTP/2 via ALPN for tls connections
+	HttpProtocolH2 HttpProtocol = "h2"
+	// HttpProtocolH2C uses HTTP/2 without tls (prior knowledge)
+	HttpProtocolH2C HttpProtocol = "h2c"
+)
+
+type HttpClientConfig struct {
+	KeepAlive                 null.Bool         `yaml:"keep_alive"`
+	MaxIdleConns              null.Int          `yaml:"max_idle_conns"`
+	MaxIdleConnsPerHost       null.Int          `yaml:"max_idle_conns_per_host"`
+	MaxConnsPerHost           null.Int          `yaml:"max_conns_per_host"`
+	IdleConnTimeout           null.String       `yaml:"idle_conn_timeout"`
+	DialTimeout               null.String       `yaml:"dial_timeout"`
+	TLSHandshakeTimeout       null.String       `yaml:"tls_handshake_timeout"`
+	ResponseHeaderTimeout     null.String       `yaml:"response_header_timeout"`
+	FollowRedirects           null.Bool         `yaml:"follow_redirects"`
+	MaxRedirects              null.Int          `yaml:"max_redirects"`
+	Compression               null.Bool         `yaml:"compression"`
+	NewConnectionPerIteration null.Bool         `yaml:"new_connection_per_iteration"`
+	Protocol                  HttpProtocol      `yaml:"protocol"`
+	TLS                       *HttpTLSConfig    `yaml:"tls"`
+	Proxy                     *HttpProxyConfig  `yaml:"proxy"`
+	Resolve                   map[string]string `yaml:"resolve"`
+}
+
+func (h *HttpClientConfig) Validate() error {
+	if h.MaxIdleConns.Valid && h.MaxIdleConns.Int64 < 0 {
+		return fmt.Errorf("'max_idle_conns' must not be negative")
+	}
+
+	if h.MaxIdleConnsPerHost.Valid && h.MaxIdleConnsPerHost.Int64 < 0 {
+		return fmt.Errorf("'max_idle_conns_per_host' must not be negative")
+	}
+
+	if h.MaxConnsPerHost.Valid && h.MaxConnsPerHost.Int64 < 0 {
+		return fmt.Errorf("'max_conns_per_host' must not be negative")
+	}
+
+	if h.MaxRedirects.Valid && h.MaxRedirects.Int64 < 0 {
+		return fmt.Errorf("'max_redirects' must not be negative")
+	}
+
+	switch h.Protocol {
+	case "", HttpProtocolHTTP1, HttpProtocolH2, HttpProtocolH2C:
+	default:
+		return fmt.Errorf("unsupported 'protocol' '%s'", h.Protocol)
+	}
+
+	if h.TLS != nil {
+		err := h.TLS.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid tls: %s", err)
+		}
+	}
+
+	if h.Proxy != nil {
+		err := h.Proxy.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid proxy: %s", err)
+		}
+
+		if h.Protocol == HttpProtocolH2C && !h.Proxy.isSOCKS5() {
+			return fmt.Errorf("protocol 'h2c' only supports 'socks5' proxies")
+		}
+
+		// http(s) proxies connect to the target themselves, so the override would only apply to the proxy
+		if len(h.Resolve) > 0 && !h.Proxy.isSOCKS5() {
+			return fmt.Errorf("'resolve' only supports 'socks5' proxies")
+		}
+	}
+
+	err := validateResolve(h.Resolve)
+	if err != nil {
+		return fmt.Errorf("invalid resolve: %s", err)
+	}
+
+	timeouts := map[string]null.String{
+		"idle_conn_timeout":       h.IdleConnTimeout,
+		"dial_timeout":            h.DialTimeout,
+		"tls_handshake_timeout":   h.TLSHandshakeTimeout,
+		"response_header_timeout": h.ResponseHeaderTimeout,
+	}
+
+	for name, timeout := range timeouts {
+		if !timeout.Valid {
+			continue
+		}
+
+		_, err := parseDurationOrNull(name, timeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (h *HttpClientConfig) newTransport(ctx context.Context, vm *otto.Otto) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = defaultMaxIdleConnsPerHost
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	if h.DialTimeout.Valid {
+		timeout, err := parseDurationOrNull("dial_timeout", h.DialTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		dialer.Timeout = timeout
+	}
+
+	var dial dialContextFunc = dialer.DialContext
+
+	if h.Proxy != nil {
+		var err error
+
+		dial, err = h.Proxy.apply(transport, dial, vm)
+		if err != nil {
+			return nil, fmt.Errorf("can't configure proxy: %s", err)
+		}
+	}
+
+	// Socks5 proxies receive the overridden address of the target
+	if len(h.Resolve) > 0 {
+		dial = resolvingDialContext(dial, h.Resolve)
+
+		// Proxies of the environment (HTTP_PROXY etc.) would bypass the override
+		if h.Proxy == nil {
+			transport.Proxy = nil
+		}
+	}
+
+	transport.DialContext = countingDialContext(dial)
+
+	if h.KeepAlive.Valid {
+		transport.DisableKeepAlives = !h.KeepAlive.Bool
+	}
+
+	if h.MaxIdleConns.Valid {
+		transport.MaxIdleConns = int(h.MaxIdleConns.Int64)
+	}
+
+	if h.MaxIdleConnsPerHost.Valid {
+		transport.MaxIdleConnsPerHost = int(h.MaxIdleConnsPerHost.Int64)
+	}
+
+	if h.MaxConnsPerHost.Valid {
+		transport.MaxConnsPerHost = int(h.MaxConnsPerHost.Int64)
+	}
+
+	if h.IdleConnTimeout.Valid {
+		timeout, err := parseDurationOrNull("idle_conn_timeout", h.IdleConnTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		transport.IdleConnTimeout = timeout
+	}
+
+	if h.TLSHandshakeTimeout.Valid {
+		timeout, err := parseDurationOrNull("tls_handshake_timeout", h.TLSHandshakeTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		transport.TLSHandshakeTimeout = timeout
+	}
+
+	if h.ResponseHeaderTimeout.Valid {
+		timeout, err := parseDurationOrNull("response_header_timeout", h.ResponseHeaderTimeout)
+		if err != nil {
+			return nil, err
+		}
+
+		transport.ResponseHeaderTimeout = timeout
+	}
+
+	if h.Compression.Valid {
+		transport.DisableCompression = !h.Compression.Bool
+	}
+
+	if h.TLS != nil {
+		tlsConfig, err := h.TLS.NewTLSConfig(ctx, vm)
+		if err != nil {
+			return nil, err
+		}
+
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	protocols := &http.Protocols{}
+
+	switch h.Protocol {
+	case HttpProtocolHTTP1:
+		protocols.SetHTTP1(true)
+	case HttpProtocolH2:
+		protocols.SetHTTP1(true)
+		protocols.SetHTTP2(true)
+	case HttpProtocolH2C:
+		// Prior knowledge, all other options of the transport still apply
+		protocols.SetUnencryptedHTTP2(true)
+	}
+
+	if h.Protocol != "" {
+		transport.Protocols = protocols
+	}
+
+	return transport, nil
+}
+
+func (h *HttpClientConfig) checkRedirect(req *http.Request, via []*http.Request) error {
+	if h.FollowRedirects.Valid && !h.FollowRedirects.Bool {
+		return http.ErrUseLastResponse
+	}
+
+	maxRedirects := int64(defaultMaxRedirects)
+	if h.MaxRedirects.Valid {
+		maxRedirects = h.MaxRedirects.Int64
+	}
+
+	if int64(len(via)) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+
+	return nil
+}
+
+// NewClient creates a new http client with its own connection pool
+func (h *HttpClientConfig) NewClient(ctx context.Context, vm *otto.Otto) (*http.Client, error) {
+	transport, err := h.newTransport(ctx, vm)
+	if err != nil {
+		return nil, fmt.Errorf("can't create http transport: %s", err)
+	}
+
+	client := &http.Client{
+		Transport:     transport,
+		CheckRedirect: h.checkRedirect,
+	}
+
+	return client, nil
+}
@@ -0,0 +1,127 @@
+package model
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+type HttpCompression string
+
+const (
+	HttpCompressionGzip    HttpCompression = "gzip"
+	HttpCompressionDeflate HttpCompression = "deflate"
+	HttpCompressionBrotli  HttpCompression = "br"
+)
+
+func (h HttpCompression) Validate() error {
+	switch h {
+	case "", HttpCompressionGzip, HttpCompressionDeflate, HttpCompressionBrotli:
+		return nil
+	default:
+		return fmt.Errorf("unsupported compression '%s', must be one of 'gzip' | 'deflate' | 'br'", h)
+	}
+}
+
+func (h HttpCompression) newWriter(writer io.Writer) (io.WriteCloser, error) {
+	switch h {
+	case HttpCompressionGzip:
+		return gzip.NewWriter(writer), nil
+	case HttpCompressionDeflate:
+		// 'deflate' in http means the zlib format
+		return zlib.NewWriterLevel(writer, flate.DefaultCompression)
+	case HttpCompressionBrotli:
+		return brotli.NewWriter(writer), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression '%s'", h)
+	}
+}
+
+// compress returns the compressed request body, it is buffered to know its length
+func (h HttpCompression) compress(body *httpRequestBody) (*httpRequestBody, error) {
+	buffer := &bytes.Buffer{}
+
+	writer, err := h.newWriter(buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = io.Copy(writer, body.Reader)
+	body.close()
+
+	if err != nil {
+		return nil, fmt.Errorf("can't compress request body: %s", err)
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return nil, fmt.Errorf("can't compress request body: %s", err)
+	}
+
+	return &httpRequestBody{
+		Reader:          buffer,
+		ContentLength:   int64(buffer.Len()),
+		ContentType:     body.ContentType,
+		ContentEncoding: string(h),
+	}, nil
+}
+
+// decodeResponseBody decompresses the response body if the http client didn't,
+// which is the case if 'Accept-Encoding' was set explicitly
+func decodeResponseBody(resp *http.Response, reader io.Reader) (io.Reader, error) {
+	if resp.Uncompressed || resp.ContentLength == 0 || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		return reader, nil
+	}
+
+	if resp.Request != nil && resp.Request.Method == http.MethodHead {
+		return reader, nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+		return reader, nil
+	case string(HttpCompressionGzip), "x-gzip":
+		return gzip.NewReader(reader)
+	case string(HttpCompressionDeflate):
+		return zlib.NewReader(reader)
+	case string(HttpCompressionBrotli):
+		return brotli.NewReader(reader), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding '%s'", resp.Header.Get("Content-Encoding"))
+	}
+}
+
+// cappedBuffer keeps the first max bytes written to it and discards the rest,
+// the buffer isn't embedded as its ReadFrom would bypass the limit in io.Copy
+type cappedBuffer struct {
+	buffer    bytes.Buffer
+	max       int64
+	truncated bool
+}
+
+func (c *cappedBuffer) Write(b []byte) (int, error) {
+	remaining := c.max - int64(c.buffer.Len())
+	if int64(len(b)) > remaining {
+		c.truncated = true
+		c.buffer.Write(b[:remaining])
+
+		return len(b), nil
+	}
+
+	return c.buffer.Write(b)
+}
+
+func (c *cappedBuffer) Bytes() []byte {
+	return c.buffer.Bytes()
+}
+
+func (c *cappedBuffer) String() string {
+	return c.buffer.String()
+}
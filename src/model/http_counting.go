@@ -0,0 +1,92 @@
+package model
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"sync/atomic"
+)
+
+// countingConn counts the bytes read from and written to the wire
+type countingConn struct {
+	net.Conn
+	bytesRead    int64
+	bytesWritten int64
+}
+
+func (c *countingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	atomic.AddInt64(&c.bytesRead, int64(n))
+
+	return n, err
+}
+
+func (c *countingConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	atomic.AddInt64(&c.bytesWritten, int64(n))
+
+	return n, err
+}
+
+func (c *countingConn) BytesRead() int64 {
+	return atomic.LoadInt64(&c.bytesRead)
+}
+
+func (c *countingConn) BytesWritten() int64 {
+	return atomic.LoadInt64(&c.bytesWritten)
+}
+
+type dialContextFunc func(ctx context.Context, network string, addr string) (net.Conn, error)
+
+// countingDialContext wraps all connections created by dial in a countingConn
+func countingDialContext(dial dialContextFunc) dialContextFunc {
+	return func(ctx context.Context, network string, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		return &countingConn{Conn: conn}, nil
+	}
+}
+
+// unwrapCountingConn returns the countingConn below a (tls) connection if existing
+func unwrapCountingConn(conn net.Conn) *countingConn {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+
+	counting, ok := conn.(*countingConn)
+	if !ok {
+		return nil
+	}
+
+	return counting
+}
+
+// countingReader counts the bytes read from a reader
+type countingReader struct {
+	io.Reader
+	bytesRead int64
+}
+
+func (c *countingReader) Read(b []byte) (int, error) {
+	n, err := c.Reader.Read(b)
+	c.bytesRead += int64(n)
+
+	return n, err
+}
+
+// countingWriter counts the bytes written, e.g. to a capped buffer
+type countingWriter struct {
+	io.Writer
+	bytesWritten int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.Writer.Write(b)
+	c.bytesWritten += int64(n)
+
+	return n, err
+}
@@ -0,0 +1,140 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/robertkrimen/otto"
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+	"gopkg.in/guregu/null.v4"
+)
+
+type HttpProxyConfig struct {
+	URL      string      `yaml:"url"`
+	Username null.String `yaml:"username"`
+	Password null.String `yaml:"password"`
+	NoProxy  null.String `yaml:"no_proxy"`
+}
+
+func (h *HttpProxyConfig) Validate() error {
+	if h.URL == "" {
+		return fmt.Errorf("missing 'url'")
+	}
+
+	proxyURL, err := url.Parse(h.URL)
+	if err != nil {
+		return fmt.Errorf("can't parse 'url': %s", err)
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return fmt.Errorf("unsupported proxy scheme '%s', must be one of 'http' | 'https' | 'socks5'", proxyURL.Scheme)
+	}
+
+	if proxyURL.Host == "" {
+		return fmt.Errorf("'url' must contain a host")
+	}
+
+	return nil
+}
+
+func (h *HttpProxyConfig) isSOCKS5() bool {
+	proxyURL, err := url.Parse(h.URL)
+
+	return err == nil && proxyURL.Scheme == "socks5"
+}
+
+// proxyURL returns the url of the proxy including the interpolated credentials
+func (h *HttpProxyConfig) proxyURL(vm *otto.Otto) (*url.URL, error) {
+	proxyURL, err := url.Parse(h.URL)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse 'url': %s", err)
+	}
+
+	if !h.Username.Valid {
+		return proxyURL, nil
+	}
+
+	username, err := interpolateString(vm, h.Username.String)
+	if err != nil {
+		return nil, fmt.Errorf("error in 'username': %s", err)
+	}
+
+	password, err := interpolateString(vm, h.Password.String)
+	if err != nil {
+		return nil, fmt.Errorf("error in 'password': %s", err)
+	}
+
+	proxyURL.User = url.UserPassword(username, password)
+
+	return proxyURL, nil
+}
+
+// apply routes all connections of the transport through the proxy,
+// http and https proxies are used by the transport itself, socks5 by the dialer
+func (h *HttpProxyConfig) apply(transport *http.Transport, dial dialContextFunc, vm *otto.Otto) (dialContextFunc, error) {
+	proxyURL, err := h.proxyURL(vm)
+	if err != nil {
+		return nil, err
+	}
+
+	if proxyURL.Scheme != "socks5" {
+		transport.Proxy = http.ProxyURL(proxyURL)
+
+		// httpproxy never proxies requests to localhost, so it's only used for 'no_proxy'
+		if h.NoProxy.Valid {
+			proxyFunc := (&httpproxy.Config{
+				HTTPProxy:  proxyURL.String(),
+				HTTPSProxy: proxyURL.String(),
+				NoProxy:    h.NoProxy.String,
+			}).ProxyFunc()
+
+			transport.Proxy = func(req *http.Request) (*url.URL, error) {
+				return proxyFunc(req.URL)
+			}
+		}
+
+		return dial, nil
+	}
+
+	var auth *proxy.Auth
+
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+
+		auth = &proxy.Auth{
+			User:     proxyURL.User.Username(),
+			Password: password,
+		}
+	}
+
+	socksDialer, err := proxy.SOCKS5("tcp", proxyURL.Host, auth, contextDialer(dial))
+	if err != nil {
+		return nil, fmt.Errorf("can't create socks5 dialer: %s", err)
+	}
+
+	perHostDialer := proxy.NewPerHost(socksDialer, contextDialer(dial))
+	if h.NoProxy.Valid {
+		perHostDialer.AddFromString(h.NoProxy.String)
+	}
+
+	transport.Proxy = nil
+
+	return perHostDialer.DialContext, nil
+}
+
+// contextDialer adapts a dialContextFunc to the dialer interfaces of golang.org/x/net/proxy
+type contextDialer dialContextFunc
+
+func (c contextDialer) Dial(network string, addr string) (net.Conn, error) {
+	return c(context.Background(), network, addr)
+}
+
+func (c contextDialer) DialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	return c(ctx, network, addr)
+}
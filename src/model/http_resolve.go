@@ -0,0 +1,44 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// validateResolve checks the entries of a 'resolve' map,
+// keys are 'host' or 'host:port', values are ip addresses
+func validateResolve(resolve map[string]string) error {
+	for host, ip := range resolve {
+		if host == "" {
+			return fmt.Errorf("resolve entry must have a host")
+		}
+
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("resolve entry '%s' must be an ip address, got '%s'", host, ip)
+		}
+	}
+
+	return nil
+}
+
+// resolvingDialContext replaces the host of dialed addresses by the ip configured
+// in resolve, an entry for 'host:port' takes precedence over one for 'host'
+func resolvingDialContext(dial dialContextFunc, resolve map[string]string) dialContextFunc {
+	return func(ctx context.Context, network string, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return dial(ctx, network, addr)
+		}
+
+		if ip, ok := resolve[addr]; ok {
+			return dial(ctx, network, net.JoinHostPort(ip, port))
+		}
+
+		if ip, ok := resolve[host]; ok {
+			return dial(ctx, network, net.JoinHostPort(ip, port))
+		}
+
+		return dial(ctx, network, addr)
+	}
+}
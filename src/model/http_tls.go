@@ -0,0 +1,126 @@
+package model
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/robertkrimen/otto"
+	"gopkg.in/guregu/null.v4"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+type HttpTLSConfig struct {
+	CAFile             null.String            `yaml:"ca_file"`
+	CertFile           null.String            `yaml:"cert_file"`
+	CertFileExpr       ExecutableStringOrNull `yaml:"cert_file_expr"`
+	KeyFile            null.String            `yaml:"key_file"`
+	KeyFileExpr        ExecutableStringOrNull `yaml:"key_file_expr"`
+	InsecureSkipVerify null.Bool              `yaml:"insecure_skip_verify"`
+	MinVersion         null.String            `yaml:"min_version"`
+	ServerName         null.String            `yaml:"server_name"`
+	ALPN               []string               `yaml:"alpn"`
+}
+
+func (h *HttpTLSConfig) Validate() error {
+	hasCert := h.CertFile.Valid || h.CertFileExpr.Valid
+	hasKey := h.KeyFile.Valid || h.KeyFileExpr.Valid
+
+	if hasCert != hasKey {
+		return fmt.Errorf("client certificate requires both of 'cert_file' | 'cert_file_expr' and 'key_file' | 'key_file_expr'")
+	}
+
+	if h.MinVersion.Valid {
+		if _, ok := tlsVersions[h.MinVersion.String]; !ok {
+			return fmt.Errorf("unsupported 'min_version' '%s'", h.MinVersion.String)
+		}
+	}
+
+	return nil
+}
+
+func (h *HttpTLSConfig) executeFilename(ctx context.Context, name string, value null.String, expr ExecutableStringOrNull, vm *otto.Otto) (string, error) {
+	if value.Valid {
+		return value.String, nil
+	}
+
+	val, err := expr.Execute(ctx, vm)
+	if err != nil {
+		return "", fmt.Errorf("error executing '%s': %s", name, err)
+	}
+
+	if !val.IsString() {
+		return "", fmt.Errorf("'%s' must return a string", name)
+	}
+
+	return val.String(), nil
+}
+
+// NewTLSConfig creates the tls config for one virtual user,
+// the client certificate expressions are evaluated in its vm
+func (h *HttpTLSConfig) NewTLSConfig(ctx context.Context, vm *otto.Otto) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	if h.CAFile.Valid {
+		caData, err := ioutil.ReadFile(h.CAFile.String)
+		if err != nil {
+			return nil, fmt.Errorf("can't read 'ca_file': %s", err)
+		}
+
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil || rootCAs == nil {
+			rootCAs = x509.NewCertPool()
+		}
+
+		if !rootCAs.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("no valid certificates found in 'ca_file'")
+		}
+
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	if h.CertFile.Valid || h.CertFileExpr.Valid {
+		certFile, err := h.executeFilename(ctx, "cert_file_expr", h.CertFile, h.CertFileExpr, vm)
+		if err != nil {
+			return nil, err
+		}
+
+		keyFile, err := h.executeFilename(ctx, "key_file_expr", h.KeyFile, h.KeyFileExpr, vm)
+		if err != nil {
+			return nil, err
+		}
+
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't load client certificate: %s", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if h.InsecureSkipVerify.Valid {
+		tlsConfig.InsecureSkipVerify = h.InsecureSkipVerify.Bool
+	}
+
+	if h.MinVersion.Valid {
+		tlsConfig.MinVersion = tlsVersions[h.MinVersion.String]
+	}
+
+	if h.ServerName.Valid {
+		tlsConfig.ServerName = h.ServerName.String
+	}
+
+	if len(h.ALPN) > 0 {
+		tlsConfig.NextProtos = h.ALPN
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,148 @@
+package model
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// httpTrace collects the timings of the phases of a single http request
+type httpTrace struct {
+	mutex           sync.Mutex
+	dnsStart        time.Time
+	dnsDone         time.Time
+	connectStart    time.Time
+	connectDone     time.Time
+	tlsStart        time.Time
+	tlsDone         time.Time
+	wroteRequest    time.Time
+	firstByte       time.Time
+	gotConn         bool
+	connReused      bool
+	errTLSHandshake error
+	// Byte counters of the connection at the time it was acquired
+	conn              *countingConn
+	startBytesRead    int64
+	startBytesWritten int64
+}
+
+func (h *httpTrace) ClientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			h.mutex.Lock()
+			defer h.mutex.Unlock()
+
+			h.dnsStart = time.Now()
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			h.mutex.Lock()
+			defer h.mutex.Unlock()
+
+			h.dnsDone = time.Now()
+		},
+		ConnectStart: func(network string, addr string) {
+			h.mutex.Lock()
+			defer h.mutex.Unlock()
+
+			// Multiple connects can be started in parallel (e.g. IPv4 & IPv6)
+			if h.connectStart.IsZero() {
+				h.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(network string, addr string, err error) {
+			h.mutex.Lock()
+			defer h.mutex.Unlock()
+
+			if err == nil {
+				h.connectDone = time.Now()
+			}
+		},
+		TLSHandshakeStart: func() {
+			h.mutex.Lock()
+			defer h.mutex.Unlock()
+
+			h.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			h.mutex.Lock()
+			defer h.mutex.Unlock()
+
+			h.tlsDone = time.Now()
+
+			if err != nil {
+				h.errTLSHandshake = err
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			h.mutex.Lock()
+			defer h.mutex.Unlock()
+
+			h.gotConn = true
+			h.connReused = info.Reused
+
+			// New connections are counted from the beginning to include handshakes
+			h.conn = unwrapCountingConn(info.Conn)
+			if h.conn != nil && info.Reused {
+				h.startBytesRead = h.conn.BytesRead()
+				h.startBytesWritten = h.conn.BytesWritten()
+			}
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			h.mutex.Lock()
+			defer h.mutex.Unlock()
+
+			h.wroteRequest = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			h.mutex.Lock()
+			defer h.mutex.Unlock()
+
+			h.firstByte = time.Now()
+		},
+	}
+}
+
+// TLSHandshakeError returns the error of a failed tls handshake
+func (h *httpTrace) TLSHandshakeError() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.errTLSHandshake
+}
+
+func durationBetween(start time.Time, end time.Time) *time.Duration {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return nil
+	}
+
+	duration := end.Sub(start)
+
+	return &duration
+}
+
+// Apply stores the collected timings in the step stats, end is the
+// time when the response body was read completely
+func (h *httpTrace) Apply(stepStats *StepExecutionStats, end time.Time) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	stepStats.DurationDNS = durationBetween(h.dnsStart, h.dnsDone)
+	stepStats.DurationConnect = durationBetween(h.connectStart, h.connectDone)
+	stepStats.DurationTLS = durationBetween(h.tlsStart, h.tlsDone)
+	stepStats.DurationTTFB = durationBetween(h.wroteRequest, h.firstByte)
+	stepStats.DurationTransfer = durationBetween(h.firstByte, end)
+
+	if h.gotConn {
+		stepStats.ConnectionReused.Scan(h.connReused)
+	}
+
+	if h.conn != nil {
+		stepStats.BytesSent.Scan(h.conn.BytesWritten() - h.startBytesWritten)
+		stepStats.BytesReceived.Scan(h.conn.BytesRead() - h.startBytesRead)
+	}
+}
+
+func newHttpTrace() *httpTrace {
+	return &httpTrace{}
+}
@@ -0,0 +1,183 @@
+package model
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/robertkrimen/otto"
+)
+
+// regexpInterpolation matches placeholders like ${var}, ${user.name} or ${items[0]}
+// and the escape sequence $${, other occurrences of ${...} are kept as they are
+var regexpInterpolation = regexp.MustCompile(`\$\$\{|\$\{\s*([A-Za-z_$][\w$]*(?:\.[A-Za-z_$][\w$]*|\[\d+\])*)\s*\}`)
+
+// regexpInterpolationPathElement matches the elements of a placeholder path
+var regexpInterpolationPathElement = regexp.MustCompile(`[A-Za-z_$][\w$]*|\[\d+\]`)
+
+// evaluatePlaceholder resolves the variable or property path of a placeholder,
+// no script is executed
+func evaluatePlaceholder(vm *otto.Otto, path string) (otto.Value, error) {
+	mutexVm.Lock()
+	defer mutexVm.Unlock()
+
+	elements := regexpInterpolationPathElement.FindAllString(path, -1)
+
+	val, err := vm.Get(elements[0])
+	if err != nil {
+		return otto.NullValue(), fmt.Errorf("can't resolve '${%s}': %s", path, err)
+	}
+
+	for _, element := range elements[1:] {
+		if !val.IsObject() {
+			return otto.NullValue(), fmt.Errorf("can't resolve '${%s}': '%s' is not an object", path, element)
+		}
+
+		val, err = val.Object().Get(strings.Trim(element, "[]"))
+		if err != nil {
+			return otto.NullValue(), fmt.Errorf("can't resolve '${%s}': %s", path, err)
+		}
+	}
+
+	if val.IsUndefined() {
+		return otto.NullValue(), fmt.Errorf("can't resolve '${%s}': not defined", path)
+	}
+
+	return val, nil
+}
+
+// interpolateStringFunc replaces all placeholders in str with their values from the vm,
+// escape is called with the offset of the placeholder in str and its value
+func interpolateStringFunc(vm *otto.Otto, str string, escape func(offset int, value string) string) (string, error) {
+	result := strings.Builder{}
+	last := 0
+
+	for _, match := range regexpInterpolation.FindAllStringSubmatchIndex(str, -1) {
+		result.WriteString(str[last:match[0]])
+		last = match[1]
+
+		if match[2] < 0 {
+			// Escaped placeholder $${
+			result.WriteString("${")
+
+			continue
+		}
+
+		val, err := evaluatePlaceholder(vm, str[match[2]:match[3]])
+		if err != nil {
+			return "", err
+		}
+
+		value := val.String()
+		if escape != nil {
+			value = escape(match[0], value)
+		}
+
+		result.WriteString(value)
+	}
+
+	result.WriteString(str[last:])
+
+	return result.String(), nil
+}
+
+// interpolateString replaces all placeholders ${...} in str with their values from the vm
+func interpolateString(vm *otto.Otto, str string) (string, error) {
+	return interpolateStringFunc(vm, str, nil)
+}
+
+// interpolateURL replaces all placeholders in the url, values are escaped
+// depending on their position: query escaped in the query and fragment, path escaped
+// in the path and inserted as they are in the scheme and host (e.g. ${baseurl}/users/${id})
+func interpolateURL(vm *otto.Otto, rawURL string) (string, error) {
+	pathStart := 0
+	if i := strings.Index(rawURL, "://"); i >= 0 {
+		pathStart = len(rawURL)
+		if j := strings.IndexAny(rawURL[i+3:], "/?#"); j >= 0 {
+			pathStart = i + 3 + j
+		}
+	}
+
+	queryStart := len(rawURL)
+	if i := strings.IndexAny(rawURL, "?#"); i >= 0 {
+		queryStart = i
+	}
+
+	return interpolateStringFunc(vm, rawURL, func(offset int, value string) string {
+		switch {
+		case offset == 0 || offset < pathStart:
+			return value
+		case offset < queryStart:
+			return url.PathEscape(value)
+		default:
+			return url.QueryEscape(value)
+		}
+	})
+}
+
+// interpolateValue replaces placeholders in all strings of a value decoded from yaml
+// and converts it to a json compatible value. Strings consisting only of a single
+// placeholder are replaced by the raw value (e.g. a number or an object).
+func interpolateValue(vm *otto.Otto, value interface{}) (interface{}, error) {
+	switch typedValue := value.(type) {
+	case string:
+		submatches := regexpInterpolation.FindStringSubmatch(typedValue)
+		if submatches != nil && submatches[0] == typedValue && submatches[1] != "" {
+			val, err := evaluatePlaceholder(vm, submatches[1])
+			if err != nil {
+				return nil, err
+			}
+
+			exported, err := val.Export()
+			if err != nil {
+				return nil, fmt.Errorf("can't export value of '%s': %s", typedValue, err)
+			}
+
+			return exported, nil
+		}
+
+		return interpolateString(vm, typedValue)
+	case map[interface{}]interface{}:
+		result := map[string]interface{}{}
+
+		for key, item := range typedValue {
+			interpolatedItem, err := interpolateValue(vm, item)
+			if err != nil {
+				return nil, err
+			}
+
+			result[fmt.Sprintf("%v", key)] = interpolatedItem
+		}
+
+		return result, nil
+	case map[string]interface{}:
+		result := map[string]interface{}{}
+
+		for key, item := range typedValue {
+			interpolatedItem, err := interpolateValue(vm, item)
+			if err != nil {
+				return nil, err
+			}
+
+			result[key] = interpolatedItem
+		}
+
+		return result, nil
+	case []interface{}:
+		result := []interface{}{}
+
+		for _, item := range typedValue {
+			interpolatedItem, err := interpolateValue(vm, item)
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, interpolatedItem)
+		}
+
+		return result, nil
+	default:
+		return value, nil
+	}
+}
@@ -0,0 +1,119 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/robertkrimen/otto"
+)
+
+func newInterpolationVM(t *testing.T) *otto.Otto {
+	vm := otto.New()
+
+	_, err := vm.Run(`
+		id = "a/b c";
+		page = 2;
+		user = {name: "Jane Doe", tags: ["x&y", "z"]};
+		base = "http://localhost:8080/api";
+	`)
+	if err != nil {
+		t.Fatalf("can't set up vm: %s", err)
+	}
+
+	return vm
+}
+
+func TestInterpolateString(t *testing.T) {
+	vm := newInterpolationVM(t)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"plain", "plain"},
+		{"${id}", "a/b c"},
+		{"${ user.name }!", "Jane Doe!"},
+		{"${user.tags[1]}", "z"},
+		{"page ${page}", "page 2"},
+		{"$${id}", "${id}"},
+		{"{\"a\": \"${1 + 2}\"}", "{\"a\": \"${1 + 2}\"}"},
+		{"${id.toUpperCase()}", "${id.toUpperCase()}"},
+	}
+
+	for _, test := range tests {
+		result, err := interpolateString(vm, test.input)
+		if err != nil {
+			t.Errorf("interpolateString(%q) returned error: %s", test.input, err)
+
+			continue
+		}
+
+		if result != test.expected {
+			t.Errorf("interpolateString(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestInterpolateStringUndefined(t *testing.T) {
+	vm := newInterpolationVM(t)
+
+	for _, input := range []string{"${missing}", "${user.missing}", "${page.value}"} {
+		_, err := interpolateString(vm, input)
+		if err == nil {
+			t.Errorf("interpolateString(%q) must return an error", input)
+		}
+	}
+}
+
+func TestInterpolateURL(t *testing.T) {
+	vm := newInterpolationVM(t)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"http://localhost/items/${id}", "http://localhost/items/a%2Fb%20c"},
+		{"http://localhost/items?id=${id}&tag=${user.tags[0]}", "http://localhost/items?id=a%2Fb+c&tag=x%26y"},
+		{"${base}/users/${user.name}", "http://localhost:8080/api/users/Jane%20Doe"},
+		{"http://${user.tags[1]}:8080/", "http://z:8080/"},
+	}
+
+	for _, test := range tests {
+		result, err := interpolateURL(vm, test.input)
+		if err != nil {
+			t.Errorf("interpolateURL(%q) returned error: %s", test.input, err)
+
+			continue
+		}
+
+		if result != test.expected {
+			t.Errorf("interpolateURL(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestInterpolateValue(t *testing.T) {
+	vm := newInterpolationVM(t)
+
+	result, err := interpolateValue(vm, map[interface{}]interface{}{
+		"page":    "${page}",
+		"name":    "Name: ${user.name}",
+		"literal": "$${page}",
+	})
+	if err != nil {
+		t.Fatalf("interpolateValue returned error: %s", err)
+	}
+
+	values := result.(map[string]interface{})
+
+	if values["page"] != int64(2) {
+		t.Errorf("expected page to be the number 2, got %#v", values["page"])
+	}
+
+	if values["name"] != "Name: Jane Doe" {
+		t.Errorf("unexpected name %#v", values["name"])
+	}
+
+	if values["literal"] != "${page}" {
+		t.Errorf("unexpected literal %#v", values["literal"])
+	}
+}
@@ -0,0 +1,275 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/indece-official/loadtest/src/report"
+	"github.com/indece-official/loadtest/src/stats"
+	"github.com/robertkrimen/otto"
+	"gopkg.in/guregu/null.v4"
+)
+
+// defaultMaxCommandOutputSize is the number of bytes of stdout and stderr captured each
+const defaultMaxCommandOutputSize = 1 << 20
+
+// commandWaitDelay is the time to wait for the output after the process was killed
+const commandWaitDelay = 1 * time.Second
+
+// CommandCodeTimeout is recorded as code in the stats if the process was killed after the timeout
+const CommandCodeTimeout = "timeout"
+
+type CommandAssertion struct {
+	Name           null.String            `yaml:"name"`
+	ExitCode       null.Int               `yaml:"exit_code"`
+	StdoutContains null.String            `yaml:"stdout_contains"`
+	Expr           ExecutableStringOrNull `yaml:"expr"`
+}
+
+// LoadTestStepCommand runs a local process, a non-zero exit code fails the step
+// unless it is checked by an 'exit_code' assertion
+type LoadTestStepCommand struct {
+	Command       string             `yaml:"command"`
+	Args          []string           `yaml:"args"`
+	Env           map[string]string  `yaml:"env"`
+	InheritEnv    null.Bool          `yaml:"inherit_env"`
+	Dir           null.String        `yaml:"dir"`
+	Stdin         null.String        `yaml:"stdin"`
+	MaxOutputSize null.Int           `yaml:"max_output_size"`
+	Timeout       null.String        `yaml:"timeout"`
+	Assertions    []CommandAssertion `yaml:"assertions"`
+}
+
+// commandResult is the result of the process as exposed to the vm
+type commandResult struct {
+	ExitCode        int    `json:"exit_code"`
+	Stdout          string `json:"stdout"`
+	Stderr          string `json:"stderr"`
+	StdoutTruncated bool   `json:"stdout_truncated"`
+	StderrTruncated bool   `json:"stderr_truncated"`
+}
+
+func (c *CommandAssertion) Verify(ctx context.Context, result *commandResult, vm *otto.Otto) error {
+	name := assertionName(c.Name)
+
+	if c.ExitCode.Valid && int64(result.ExitCode) != c.ExitCode.Int64 {
+		return fmt.Errorf("assertion %son exit code failed: expected %d, got %d", name, c.ExitCode.Int64, result.ExitCode)
+	}
+
+	if c.StdoutContains.Valid && !strings.Contains(result.Stdout, c.StdoutContains.String) {
+		return fmt.Errorf("assertion %son stdout failed: '%s' not found", name, c.StdoutContains.String)
+	}
+
+	return verifyExprAssertion(ctx, c.Name, c.Expr, vm)
+}
+
+func (l *LoadTestStepCommand) Validate() error {
+	if l.Command == "" {
+		return fmt.Errorf("missing 'command'")
+	}
+
+	if l.MaxOutputSize.Valid && l.MaxOutputSize.Int64 < 0 {
+		return fmt.Errorf("'max_output_size' must not be negative")
+	}
+
+	if l.Timeout.Valid {
+		_, err := parseDurationOrNull("timeout", l.Timeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hasExitCodeAssertion returns true if the exit code is checked by the assertions,
+// otherwise all exit codes except 0 fail the step
+func (l *LoadTestStepCommand) hasExitCodeAssertion() bool {
+	for _, assertion := range l.Assertions {
+		if assertion.ExitCode.Valid {
+			return true
+		}
+	}
+
+	return false
+}
+
+// buildEnv returns the environment of the process, the variables of 'env'
+// are added to the environment of the loadtest unless 'inherit_env' is false
+func (l *LoadTestStepCommand) buildEnv(vm *otto.Otto) ([]string, error) {
+	env := []string{}
+	if !l.InheritEnv.Valid || l.InheritEnv.Bool {
+		env = os.Environ()
+	}
+
+	names := []string{}
+	for name := range l.Env {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		value, err := interpolateString(vm, l.Env[name])
+		if err != nil {
+			return nil, fmt.Errorf("error in env '%s': %s", name, err)
+		}
+
+		env = append(env, name+"="+value)
+	}
+
+	return env, nil
+}
+
+func (l *LoadTestStepCommand) buildCommand(ctx context.Context, vm *otto.Otto) (*exec.Cmd, error) {
+	command, err := interpolateString(vm, l.Command)
+	if err != nil {
+		return nil, fmt.Errorf("error in 'command': %s", err)
+	}
+
+	args := []string{}
+	for i, arg := range l.Args {
+		value, err := interpolateString(vm, arg)
+		if err != nil {
+			return nil, fmt.Errorf("error in arg %d: %s", i+1, err)
+		}
+
+		args = append(args, value)
+	}
+
+	cmd := exec.CommandContext(ctx, command, args...)
+	cmd.WaitDelay = commandWaitDelay
+
+	cmd.Env, err = l.buildEnv(vm)
+	if err != nil {
+		return nil, err
+	}
+
+	if l.Dir.Valid {
+		cmd.Dir, err = interpolateString(vm, l.Dir.String)
+		if err != nil {
+			return nil, fmt.Errorf("error in 'dir': %s", err)
+		}
+	}
+
+	return cmd, nil
+}
+
+// assignResponseObject sets 'response' with the exit code and output in the vm
+func (l *LoadTestStepCommand) assignResponseObject(ctx context.Context, result *commandResult, vm *otto.Otto) error {
+	return assignVariable(ctx, vm, "response", result)
+}
+
+func (l *LoadTestStepCommand) Execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error) {
+	stepStats := &StepExecutionStats{}
+
+	qctx := ctx
+	if l.Timeout.Valid {
+		timeout, err := parseDurationOrNull("timeout", l.Timeout)
+		if err != nil {
+			return stepStats, err
+		}
+
+		var cancel func()
+
+		qctx, cancel = context.WithTimeout(qctx, timeout)
+		defer cancel()
+	}
+
+	cmd, err := l.buildCommand(qctx, vm)
+	if err != nil {
+		return stepStats, err
+	}
+
+	if l.Stdin.Valid {
+		stdin, err := interpolateString(vm, l.Stdin.String)
+		if err != nil {
+			return stepStats, fmt.Errorf("error in 'stdin': %s", err)
+		}
+
+		cmd.Stdin = strings.NewReader(stdin)
+		stepStats.BytesSent.Scan(int64(len(stdin)))
+	}
+
+	maxOutputSize := int64(defaultMaxCommandOutputSize)
+	if l.MaxOutputSize.Valid {
+		maxOutputSize = l.MaxOutputSize.Int64
+	}
+
+	stdout := &cappedBuffer{max: maxOutputSize}
+	stderr := &cappedBuffer{max: maxOutputSize}
+
+	stdoutWriter := &countingWriter{Writer: stdout}
+	stderrWriter := &countingWriter{Writer: stderr}
+
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
+
+	start := time.Now()
+
+	err = cmd.Run()
+
+	durationRequest := time.Since(start)
+	stepStats.DurationRequest = &durationRequest
+
+	stepStats.BytesReceived.Scan(stdoutWriter.bytesWritten + stderrWriter.bytesWritten)
+
+	if ctx.Err() != nil {
+		return stepStats, ctx.Err()
+	}
+
+	if qctx.Err() != nil {
+		stepStats.Code.Scan(CommandCodeTimeout)
+
+		return stepStats, fmt.Errorf("command killed after timeout of %s", l.Timeout.String)
+	}
+
+	// ErrWaitDelay means the process exited but a child kept the output open,
+	// the exit code is still valid
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) && !errors.Is(err, exec.ErrWaitDelay) {
+		return stepStats, fmt.Errorf("can't run command: %s", err)
+	}
+
+	result := &commandResult{
+		ExitCode:        cmd.ProcessState.ExitCode(),
+		Stdout:          stdout.String(),
+		Stderr:          stderr.String(),
+		StdoutTruncated: stdout.truncated,
+		StderrTruncated: stderr.truncated,
+	}
+
+	stepStats.Code.Scan(strconv.Itoa(result.ExitCode))
+
+	if result.ExitCode != 0 && !l.hasExitCodeAssertion() {
+		stderr := strings.TrimSpace(result.Stderr)
+		if stderr == "" {
+			return stepStats, fmt.Errorf("command failed with exit code %d", result.ExitCode)
+		}
+
+		return stepStats, fmt.Errorf("command failed with exit code %d: %s", result.ExitCode, stderr)
+	}
+
+	err = l.assignResponseObject(ctx, result, vm)
+	if err != nil {
+		return stepStats, err
+	}
+
+	for _, assertion := range l.Assertions {
+		err = assertion.Verify(ctx, result, vm)
+		if err != nil {
+			return stepStats, err
+		}
+	}
+
+	return stepStats, nil
+}
+
+var _ IRunnableStep = (*LoadTestStepCommand)(nil)
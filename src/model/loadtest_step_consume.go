@@ -0,0 +1,193 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/indece-official/loadtest/src/report"
+	"github.com/indece-official/loadtest/src/stats"
+	"github.com/robertkrimen/otto"
+	"gopkg.in/guregu/null.v4"
+)
+
+const defaultConsumeTimeout = 10 * time.Second
+
+type MessageAssertion struct {
+	Name null.String            `yaml:"name"`
+	Expr ExecutableStringOrNull `yaml:"expr"`
+}
+
+// LoadTestStepConsume receives messages, the subscription is kept per virtual user
+// across steps, a count of 0 only subscribes (e.g. before messages get published)
+type LoadTestStepConsume struct {
+	Broker      null.String            `yaml:"broker"`
+	Destination string                 `yaml:"destination"`
+	Group       null.String            `yaml:"group"`
+	Count       null.Int               `yaml:"count"`
+	Until       ExecutableStringOrNull `yaml:"until"`
+	Timeout     null.String            `yaml:"timeout"`
+	Close       null.Bool              `yaml:"close"`
+	Assertions  []MessageAssertion     `yaml:"assertions"`
+}
+
+func (m *MessageAssertion) Verify(ctx context.Context, vm *otto.Otto) error {
+	return verifyExprAssertion(ctx, m.Name, m.Expr, vm)
+}
+
+func (l *LoadTestStepConsume) Validate() error {
+	if l.Destination == "" {
+		return fmt.Errorf("missing 'destination'")
+	}
+
+	if l.Count.Valid && l.Count.Int64 < 0 {
+		return fmt.Errorf("'count' must not be negative")
+	}
+
+	if l.Timeout.Valid {
+		_, err := parseDurationOrNull("timeout", l.Timeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// matches sets the received message as variable 'message' and evaluates 'until'
+func (l *LoadTestStepConsume) matches(ctx context.Context, message *brokerMessage, latency *time.Duration, vm *otto.Otto) (bool, error) {
+	var latencyMilliseconds interface{}
+	if latency != nil {
+		latencyMilliseconds = latency.Milliseconds()
+	}
+
+	return matchesUntil(ctx, vm, "message", map[string]interface{}{
+		"body":    string(message.Payload),
+		"headers": message.Headers,
+		"latency": latencyMilliseconds,
+	}, l.Until)
+}
+
+func (l *LoadTestStepConsume) Execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error) {
+	stepStats := &StepExecutionStats{}
+
+	virtualUser := virtualUserFromContext(ctx)
+	brokers := messageBrokersFromContext(ctx)
+	if virtualUser == nil || brokers == nil {
+		return stepStats, fmt.Errorf("consume steps must run within a load test")
+	}
+
+	name := defaultMessageBroker
+	if l.Broker.Valid {
+		name = l.Broker.String
+	}
+
+	broker, err := brokers.broker(ctx, name, vm)
+	if err != nil {
+		return stepStats, err
+	}
+
+	destination, err := interpolateString(vm, l.Destination)
+	if err != nil {
+		return stepStats, fmt.Errorf("error in 'destination': %s", err)
+	}
+
+	key := fmt.Sprintf("consume:%s:%s:%s", name, destination, l.Group.String)
+
+	subscription, _ := virtualUser.connection(key).(messageSubscription)
+	if subscription == nil {
+		subscription, err = broker.Subscribe(ctx, destination, l.Group.String)
+		if err != nil {
+			return stepStats, fmt.Errorf("can't subscribe to '%s': %s", destination, err)
+		}
+
+		virtualUser.setConnection(key, subscription)
+		brokers.tracker.subscribe()
+	}
+
+	timeout := defaultConsumeTimeout
+	if l.Timeout.Valid {
+		timeout, err = parseDurationOrNull("timeout", l.Timeout)
+		if err != nil {
+			return stepStats, err
+		}
+	}
+
+	count := int64(1)
+	if l.Count.Valid {
+		count = l.Count.Int64
+	}
+
+	qctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var messagesReceived int64
+	var messagesDuplicate int64
+	var bytesReceived int64
+
+	matched := int64(0)
+
+	for matched < count {
+		message, err := subscription.Next(qctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return stepStats, ctx.Err()
+			}
+
+			if qctx.Err() != nil {
+				return stepStats, fmt.Errorf("timeout after %s waiting for message (%d of %d received)", timeout, matched, count)
+			}
+
+			virtualUser.removeConnection(key)
+			subscription.Close()
+
+			return stepStats, fmt.Errorf("can't receive message: %s", err)
+		}
+
+		messagesReceived++
+		bytesReceived += int64(len(message.Payload))
+
+		stepStats.MessagesReceived.Scan(messagesReceived)
+		stepStats.BytesReceived.Scan(bytesReceived)
+
+		// Only messages matched with their publish contribute to the end-to-end latency
+		latency, duplicate := brokers.tracker.consume(message)
+		if latency != nil {
+			stepStats.DurationsEndToEnd = append(stepStats.DurationsEndToEnd, *latency)
+		}
+
+		if duplicate {
+			messagesDuplicate++
+			stepStats.MessagesDuplicate.Scan(messagesDuplicate)
+		}
+
+		isMatch, err := l.matches(ctx, message, latency, vm)
+		if err != nil {
+			return stepStats, err
+		}
+
+		if isMatch {
+			matched++
+		}
+	}
+
+	if l.Close.Valid && l.Close.Bool {
+		virtualUser.removeConnection(key)
+
+		err = subscription.Close()
+		if err != nil {
+			return stepStats, fmt.Errorf("can't close subscription: %s", err)
+		}
+	}
+
+	for _, assertion := range l.Assertions {
+		err = assertion.Verify(ctx, vm)
+		if err != nil {
+			return stepStats, err
+		}
+	}
+
+	return stepStats, nil
+}
+
+var _ IRunnableStep = (*LoadTestStepConsume)(nil)
@@ -0,0 +1,260 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/indece-official/loadtest/src/report"
+	"github.com/indece-official/loadtest/src/stats"
+	"github.com/miekg/dns"
+	"github.com/robertkrimen/otto"
+	"gopkg.in/guregu/null.v4"
+)
+
+const defaultDNSPort = "53"
+const defaultDNSTimeout = 5 * time.Second
+
+type DNSNetwork string
+
+const (
+	DNSNetworkUDP DNSNetwork = "udp"
+	DNSNetworkTCP DNSNetwork = "tcp"
+)
+
+type DNSAssertion struct {
+	Name   null.String            `yaml:"name"`
+	Rcode  null.String            `yaml:"rcode"`
+	Answer null.String            `yaml:"answer"`
+	Expr   ExecutableStringOrNull `yaml:"expr"`
+}
+
+type LoadTestStepDNS struct {
+	Server     string         `yaml:"server"`
+	Query      string         `yaml:"query"`
+	Type       null.String    `yaml:"type"`
+	Network    DNSNetwork     `yaml:"network"`
+	Recursion  null.Bool      `yaml:"recursion"`
+	Timeout    null.String    `yaml:"timeout"`
+	Assertions []DNSAssertion `yaml:"assertions"`
+}
+
+// dnsAnswer is an answer record as exposed to the vm
+type dnsAnswer struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	TTL      uint32 `json:"ttl"`
+	Value    string `json:"value"`
+	Priority uint16 `json:"priority,omitempty"`
+	Weight   uint16 `json:"weight,omitempty"`
+	Port     uint16 `json:"port,omitempty"`
+}
+
+func newDNSAnswer(rr dns.RR) *dnsAnswer {
+	header := rr.Header()
+
+	answer := &dnsAnswer{
+		Name: header.Name,
+		Type: dns.TypeToString[header.Rrtype],
+		TTL:  header.Ttl,
+	}
+
+	switch record := rr.(type) {
+	case *dns.A:
+		answer.Value = record.A.String()
+	case *dns.AAAA:
+		answer.Value = record.AAAA.String()
+	case *dns.CNAME:
+		answer.Value = record.Target
+	case *dns.TXT:
+		answer.Value = strings.Join(record.Txt, "")
+	case *dns.SRV:
+		answer.Value = record.Target
+		answer.Priority = record.Priority
+		answer.Weight = record.Weight
+		answer.Port = record.Port
+	default:
+		answer.Value = strings.TrimPrefix(rr.String(), header.String())
+	}
+
+	return answer
+}
+
+// Verify checks the assertion, 'answer' must equal the value of one of the answers
+func (d *DNSAssertion) Verify(ctx context.Context, rcode string, answers []*dnsAnswer, vm *otto.Otto) error {
+	name := assertionName(d.Name)
+
+	if d.Rcode.Valid && !strings.EqualFold(d.Rcode.String, rcode) {
+		return fmt.Errorf("assertion %son dns rcode failed: expected '%s', got '%s'", name, d.Rcode.String, rcode)
+	}
+
+	if d.Answer.Valid {
+		found := false
+
+		for _, answer := range answers {
+			if answer.Value == d.Answer.String {
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("assertion %son dns answers failed: '%s' not found", name, d.Answer.String)
+		}
+	}
+
+	return verifyExprAssertion(ctx, d.Name, d.Expr, vm)
+}
+
+func (l *LoadTestStepDNS) Validate() error {
+	if l.Server == "" {
+		return fmt.Errorf("missing 'server'")
+	}
+
+	if l.Query == "" {
+		return fmt.Errorf("missing 'query'")
+	}
+
+	if l.Type.Valid {
+		if _, ok := dns.StringToType[strings.ToUpper(l.Type.String)]; !ok {
+			return fmt.Errorf("invalid type '%s'", l.Type.String)
+		}
+	}
+
+	switch l.Network {
+	case "", DNSNetworkUDP, DNSNetworkTCP:
+	default:
+		return fmt.Errorf("invalid network '%s', must be one of 'udp' | 'tcp'", l.Network)
+	}
+
+	if l.Timeout.Valid {
+		_, err := parseDurationOrNull("timeout", l.Timeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, assertion := range l.Assertions {
+		if assertion.Rcode.Valid {
+			if _, ok := dns.StringToRcode[strings.ToUpper(assertion.Rcode.String)]; !ok {
+				return fmt.Errorf("invalid rcode '%s' in assertion", assertion.Rcode.String)
+			}
+		}
+	}
+
+	return nil
+}
+
+// hasRcodeAssertion returns true if the rcode is checked by the assertions,
+// otherwise all rcodes except NOERROR fail the step
+func (l *LoadTestStepDNS) hasRcodeAssertion() bool {
+	for _, assertion := range l.Assertions {
+		if assertion.Rcode.Valid {
+			return true
+		}
+	}
+
+	return false
+}
+
+// assignResponseObject sets 'response' with the rcode, flags and answers in the vm
+func (l *LoadTestStepDNS) assignResponseObject(ctx context.Context, response *dns.Msg, answers []*dnsAnswer, vm *otto.Otto) error {
+	return assignVariable(ctx, vm, "response", map[string]interface{}{
+		"rcode":         dns.RcodeToString[response.Rcode],
+		"authoritative": response.Authoritative,
+		"truncated":     response.Truncated,
+		"answers":       answers,
+	})
+}
+
+func (l *LoadTestStepDNS) Execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error) {
+	stepStats := &StepExecutionStats{}
+
+	server, err := interpolateString(vm, l.Server)
+	if err != nil {
+		return stepStats, fmt.Errorf("error in 'server': %s", err)
+	}
+
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, defaultDNSPort)
+	}
+
+	query, err := interpolateString(vm, l.Query)
+	if err != nil {
+		return stepStats, fmt.Errorf("error in 'query': %s", err)
+	}
+
+	queryType := dns.TypeA
+	if l.Type.Valid {
+		queryType = dns.StringToType[strings.ToUpper(l.Type.String)]
+	}
+
+	network := DNSNetworkUDP
+	if l.Network != "" {
+		network = l.Network
+	}
+
+	timeout := defaultDNSTimeout
+	if l.Timeout.Valid {
+		timeout, err = parseDurationOrNull("timeout", l.Timeout)
+		if err != nil {
+			return stepStats, err
+		}
+	}
+
+	request := &dns.Msg{}
+	request.SetQuestion(dns.Fqdn(query), queryType)
+	request.RecursionDesired = !l.Recursion.Valid || l.Recursion.Bool
+
+	client := &dns.Client{
+		Net:     string(network),
+		Timeout: timeout,
+	}
+
+	stepStats.Protocol.Scan(string(network))
+
+	start := time.Now()
+
+	response, _, err := client.ExchangeContext(ctx, request, server)
+
+	durationRequest := time.Since(start)
+	stepStats.DurationRequest = &durationRequest
+
+	if err != nil {
+		return stepStats, fmt.Errorf("dns query failed: %s", err)
+	}
+
+	rcode := dns.RcodeToString[response.Rcode]
+
+	stepStats.Code.Scan(rcode)
+	stepStats.BytesSent.Scan(int64(request.Len()))
+	stepStats.BytesReceived.Scan(int64(response.Len()))
+
+	if response.Rcode != dns.RcodeSuccess && !l.hasRcodeAssertion() {
+		return stepStats, fmt.Errorf("dns query failed with rcode '%s'", rcode)
+	}
+
+	answers := []*dnsAnswer{}
+	for _, rr := range response.Answer {
+		answers = append(answers, newDNSAnswer(rr))
+	}
+
+	err = l.assignResponseObject(ctx, response, answers, vm)
+	if err != nil {
+		return stepStats, err
+	}
+
+	for _, assertion := range l.Assertions {
+		err = assertion.Verify(ctx, rcode, answers, vm)
+		if err != nil {
+			return stepStats, err
+		}
+	}
+
+	return stepStats, nil
+}
+
+var _ IRunnableStep = (*LoadTestStepDNS)(nil)
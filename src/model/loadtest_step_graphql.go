@@ -0,0 +1,221 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/indece-official/loadtest/src/report"
+	"github.com/indece-official/loadtest/src/stats"
+	"github.com/robertkrimen/otto"
+	"gopkg.in/guregu/null.v4"
+)
+
+// graphqlOperationNameRegex extracts the name of the first operation in a query
+var graphqlOperationNameRegex = regexp.MustCompile(`^\s*(?:query|mutation|subscription)\s+([_A-Za-z][_0-9A-Za-z]*)`)
+
+// graphqlAnonymousOperation is recorded in the stats for queries without operation name
+const graphqlAnonymousOperation = "anonymous"
+
+// LoadTestStepGraphQL posts a query to a GraphQL endpoint, responses containing
+// 'errors' fail the step unless 'allow_errors' is set
+type LoadTestStepGraphQL struct {
+	URL                 string          `yaml:"url"`
+	Query               string          `yaml:"query"`
+	OperationName       null.String     `yaml:"operation_name"`
+	Variables           interface{}     `yaml:"variables"`
+	Headers             []HttpHeader    `yaml:"headers"`
+	Auth                *HttpAuth       `yaml:"auth"`
+	AllowErrors         null.Bool       `yaml:"allow_errors"`
+	MaxResponseBodySize null.Int        `yaml:"max_response_body_size"`
+	Timeout             null.String     `yaml:"timeout"`
+	Assertions          []HttpAssertion `yaml:"assertions"`
+}
+
+type graphqlError struct {
+	Message string `json:"message"`
+}
+
+type graphqlResponse struct {
+	Data   interface{}    `json:"data"`
+	Errors []graphqlError `json:"errors"`
+}
+
+func (l *LoadTestStepGraphQL) Validate() error {
+	if l.URL == "" {
+		return fmt.Errorf("missing 'url'")
+	}
+
+	if l.Query == "" {
+		return fmt.Errorf("missing 'query'")
+	}
+
+	if l.Auth != nil {
+		err := l.Auth.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid auth: %s", err)
+		}
+	}
+
+	if l.MaxResponseBodySize.Valid && l.MaxResponseBodySize.Int64 < 0 {
+		return fmt.Errorf("'max_response_body_size' must not be negative")
+	}
+
+	if l.Timeout.Valid {
+		_, err := parseDurationOrNull("timeout", l.Timeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// operationName returns the name under which the operation is recorded in the stats
+func (l *LoadTestStepGraphQL) operationName(operationName string) string {
+	if operationName != "" {
+		return operationName
+	}
+
+	match := graphqlOperationNameRegex.FindStringSubmatch(l.Query)
+	if match != nil {
+		return match[1]
+	}
+
+	return graphqlAnonymousOperation
+}
+
+// httpStep builds the http request posting the query
+func (l *LoadTestStepGraphQL) httpStep(operationName string) *LoadTestStepHttp {
+	body := map[string]interface{}{
+		"query": l.Query,
+	}
+
+	if operationName != "" {
+		body["operationName"] = operationName
+	}
+
+	if l.Variables != nil {
+		body["variables"] = l.Variables
+	}
+
+	headers := l.Headers
+
+	hasAccept := false
+	for _, header := range headers {
+		if http.CanonicalHeaderKey(header.Name) == "Accept" {
+			hasAccept = true
+		}
+	}
+
+	if !hasAccept {
+		headers = append([]HttpHeader{{
+			Name:  "Accept",
+			Value: null.StringFrom("application/json"),
+		}}, headers...)
+	}
+
+	return &LoadTestStepHttp{
+		URL:    null.StringFrom(l.URL),
+		Method: HttpMethodPost,
+		RequestBody: &HttpBody{
+			JSON: body,
+		},
+		Headers:             headers,
+		Auth:                l.Auth,
+		MaxResponseBodySize: l.MaxResponseBodySize,
+		Timeout:             l.Timeout,
+		Assertions:          l.Assertions,
+	}
+}
+
+// assignResult sets 'response.data' and 'response.errors' in the vm
+func (l *LoadTestStepGraphQL) assignResult(ctx context.Context, result *graphqlResponse, vm *otto.Otto) error {
+	data, err := json.Marshal(result.Data)
+	if err != nil {
+		return fmt.Errorf("can't encode data: %s", err)
+	}
+
+	errs, err := json.Marshal(result.Errors)
+	if err != nil {
+		return fmt.Errorf("can't encode errors: %s", err)
+	}
+
+	mutexVm.Lock()
+	defer mutexVm.Unlock()
+
+	_, err = runScript(ctx, vm, fmt.Sprintf("response.data = %s; response.errors = %s", data, errs))
+	if err != nil {
+		return fmt.Errorf("can't set response data: %s", err)
+	}
+
+	return nil
+}
+
+func (l *LoadTestStepGraphQL) Execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error) {
+	operationName := ""
+	if l.OperationName.Valid {
+		var err error
+
+		operationName, err = interpolateString(vm, l.OperationName.String)
+		if err != nil {
+			return nil, fmt.Errorf("error in 'operation_name': %s", err)
+		}
+	}
+
+	httpStep := l.httpStep(operationName)
+
+	stepStats, resp, capturedBody, bodyLength, err := httpStep.roundTrip(ctx, vm)
+
+	// Requests failing before a response was received have no duration
+	if stepStats != nil && stepStats.DurationRequest != nil {
+		stepStats.Operations = []*stats.StepOperation{{
+			Name:     l.operationName(operationName),
+			Duration: *stepStats.DurationRequest,
+		}}
+	}
+
+	if err != nil {
+		return stepStats, err
+	}
+
+	if capturedBody == nil || capturedBody.truncated {
+		return stepStats, fmt.Errorf("graphql response exceeds 'max_response_body_size'")
+	}
+
+	result := &graphqlResponse{}
+
+	err = json.Unmarshal(capturedBody.Bytes(), result)
+	if err != nil {
+		return stepStats, fmt.Errorf("can't decode graphql response (status %d): %s", resp.StatusCode, err)
+	}
+
+	err = httpStep.assignResponseObject(ctx, resp, capturedBody, vm)
+	if err != nil {
+		return stepStats, fmt.Errorf("can't assign response object to vm: %s", err)
+	}
+
+	err = l.assignResult(ctx, result, vm)
+	if err != nil {
+		return stepStats, err
+	}
+
+	if len(result.Errors) > 0 && !(l.AllowErrors.Valid && l.AllowErrors.Bool) {
+		return stepStats, fmt.Errorf("graphql response contains %d error(s): %s", len(result.Errors), result.Errors[0].Message)
+	}
+
+	body := capturedBody.Bytes()
+
+	for _, assertion := range l.Assertions {
+		err = assertion.Verify(ctx, resp, bodyLength, body, vm)
+		if err != nil {
+			return stepStats, err
+		}
+	}
+
+	return stepStats, nil
+}
+
+var _ IRunnableStep = (*LoadTestStepGraphQL)(nil)
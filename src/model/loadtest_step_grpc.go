@@ -0,0 +1,366 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/indece-official/loadtest/src/report"
+	"github.com/indece-official/loadtest/src/stats"
+	"github.com/robertkrimen/otto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"gopkg.in/guregu/null.v4"
+)
+
+type GrpcAssertion struct {
+	Name null.String            `yaml:"name"`
+	Code null.String            `yaml:"code"`
+	Expr ExecutableStringOrNull `yaml:"expr"`
+}
+
+type LoadTestStepGrpc struct {
+	Address     string          `yaml:"address"`
+	Method      string          `yaml:"method"`
+	ProtoFiles  []string        `yaml:"proto_files"`
+	ImportPaths []string        `yaml:"import_paths"`
+	TLS         *HttpTLSConfig  `yaml:"tls"`
+	Metadata    []HttpHeader    `yaml:"metadata"`
+	Request     interface{}     `yaml:"request"`
+	Timeout     null.String     `yaml:"timeout"`
+	Assertions  []GrpcAssertion `yaml:"assertions"`
+
+	mutexMethods sync.Mutex
+	methods      map[string]protoreflect.MethodDescriptor
+}
+
+// Verify checks the assertion, the code can be given by name (e.g. 'NotFound') or number
+func (g *GrpcAssertion) Verify(ctx context.Context, code codes.Code, vm *otto.Otto) error {
+	name := assertionName(g.Name)
+
+	if g.Code.Valid && g.Code.String != code.String() && g.Code.String != strconv.Itoa(int(code)) {
+		return fmt.Errorf("assertion %son grpc status code failed: expected '%s', got '%s'", name, g.Code.String, code)
+	}
+
+	return verifyExprAssertion(ctx, g.Name, g.Expr, vm)
+}
+
+func (l *LoadTestStepGrpc) Validate() error {
+	if l.Address == "" {
+		return fmt.Errorf("missing 'address'")
+	}
+
+	_, _, err := splitGrpcMethod(l.Method)
+	if err != nil {
+		return err
+	}
+
+	if l.TLS != nil {
+		err := l.TLS.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid tls: %s", err)
+		}
+	}
+
+	if l.Timeout.Valid {
+		_, err := parseDurationOrNull("timeout", l.Timeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hasCodeAssertion returns true if the status code is checked by the assertions,
+// otherwise all codes except OK fail the step
+func (l *LoadTestStepGrpc) hasCodeAssertion() bool {
+	for _, assertion := range l.Assertions {
+		if assertion.Code.Valid {
+			return true
+		}
+	}
+
+	return false
+}
+
+// connection returns the client connection of the virtual user for the address
+func (l *LoadTestStepGrpc) connection(ctx context.Context, virtualUser *VirtualUser, address string, vm *otto.Otto) (*grpc.ClientConn, error) {
+	key := "grpc:" + address
+	if l.TLS != nil {
+		key += ":tls"
+	}
+
+	if conn, ok := virtualUser.connection(key).(*grpc.ClientConn); ok {
+		return conn, nil
+	}
+
+	transportCredentials := insecure.NewCredentials()
+
+	if l.TLS != nil {
+		tlsConfig, err := l.TLS.NewTLSConfig(ctx, vm)
+		if err != nil {
+			return nil, err
+		}
+
+		transportCredentials = credentials.NewTLS(tlsConfig)
+	}
+
+	conn, err := grpc.NewClient(
+		address,
+		grpc.WithTransportCredentials(transportCredentials),
+		grpc.WithStatsHandler(&grpcStatsHandler{}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("can't create grpc client: %s", err)
+	}
+
+	virtualUser.setConnection(key, conn)
+
+	return conn, nil
+}
+
+// method returns the descriptor of the method, which is resolved once per address
+func (l *LoadTestStepGrpc) method(ctx context.Context, conn *grpc.ClientConn, address string) (protoreflect.MethodDescriptor, error) {
+	l.mutexMethods.Lock()
+	defer l.mutexMethods.Unlock()
+
+	if l.methods == nil {
+		l.methods = map[string]protoreflect.MethodDescriptor{}
+	}
+
+	if method, ok := l.methods[address]; ok {
+		return method, nil
+	}
+
+	var method protoreflect.MethodDescriptor
+	var err error
+
+	if len(l.ProtoFiles) > 0 {
+		method, err = grpcMethodFromProtoFiles(ctx, l.ProtoFiles, l.ImportPaths, l.Method)
+	} else {
+		method, err = grpcMethodFromReflection(ctx, conn, l.Method)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if method.IsStreamingClient() {
+		return nil, fmt.Errorf("client streaming method '%s' is not supported", l.Method)
+	}
+
+	l.methods[address] = method
+
+	return method, nil
+}
+
+func (l *LoadTestStepGrpc) buildRequest(method protoreflect.MethodDescriptor, vm *otto.Otto) (*dynamicpb.Message, error) {
+	request := dynamicpb.NewMessage(method.Input())
+
+	if l.Request == nil {
+		return request, nil
+	}
+
+	value, err := interpolateValue(vm, l.Request)
+	if err != nil {
+		return nil, fmt.Errorf("error in 'request': %s", err)
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("can't encode 'request': %s", err)
+	}
+
+	err = protojson.Unmarshal(data, request)
+	if err != nil {
+		return nil, fmt.Errorf("can't convert 'request' to '%s': %s", method.Input().FullName(), err)
+	}
+
+	return request, nil
+}
+
+// assignResponseObject sets 'response' in the vm, 'message' holds the first and
+// 'messages' all received messages
+func (l *LoadTestStepGrpc) assignResponseObject(ctx context.Context, grpcStatus *status.Status, messages []json.RawMessage, vm *otto.Otto) error {
+	var message json.RawMessage = []byte("null")
+	if len(messages) > 0 {
+		message = messages[0]
+	}
+
+	return assignVariable(ctx, vm, "response", map[string]interface{}{
+		"code":          grpcStatus.Code().String(),
+		"statusmessage": grpcStatus.Message(),
+		"message":       message,
+		"messages":      messages,
+	})
+}
+
+// call executes the method and returns the responses as json
+func (l *LoadTestStepGrpc) call(ctx context.Context, conn *grpc.ClientConn, method protoreflect.MethodDescriptor, request *dynamicpb.Message) ([]json.RawMessage, error) {
+	fullMethod := fmt.Sprintf("/%s/%s", method.Parent().FullName(), method.Name())
+	messages := []json.RawMessage{}
+
+	appendMessage := func(response *dynamicpb.Message) error {
+		data, err := protojson.Marshal(response)
+		if err != nil {
+			return fmt.Errorf("can't encode response message: %s", err)
+		}
+
+		messages = append(messages, data)
+
+		return nil
+	}
+
+	if !method.IsStreamingServer() {
+		response := dynamicpb.NewMessage(method.Output())
+
+		err := conn.Invoke(ctx, fullMethod, request, response)
+		if err != nil {
+			return messages, err
+		}
+
+		return messages, appendMessage(response)
+	}
+
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, fullMethod)
+	if err != nil {
+		return messages, err
+	}
+
+	err = stream.SendMsg(request)
+	if err != nil {
+		return messages, err
+	}
+
+	err = stream.CloseSend()
+	if err != nil {
+		return messages, err
+	}
+
+	for {
+		response := dynamicpb.NewMessage(method.Output())
+
+		err = stream.RecvMsg(response)
+		if err == io.EOF {
+			return messages, nil
+		}
+
+		if err != nil {
+			return messages, err
+		}
+
+		err = appendMessage(response)
+		if err != nil {
+			return messages, err
+		}
+	}
+}
+
+func (l *LoadTestStepGrpc) Execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error) {
+	stepStats := &StepExecutionStats{}
+
+	virtualUser := virtualUserFromContext(ctx)
+	if virtualUser == nil {
+		return stepStats, fmt.Errorf("grpc steps must run within a load test")
+	}
+
+	address, err := interpolateString(vm, l.Address)
+	if err != nil {
+		return stepStats, fmt.Errorf("error in 'address': %s", err)
+	}
+
+	conn, err := l.connection(ctx, virtualUser, address, vm)
+	if err != nil {
+		return stepStats, err
+	}
+
+	qctx := ctx
+	if l.Timeout.Valid {
+		timeout, err := parseDurationOrNull("timeout", l.Timeout)
+		if err != nil {
+			return stepStats, err
+		}
+
+		var cancel func()
+
+		qctx, cancel = context.WithTimeout(qctx, timeout)
+		defer cancel()
+	}
+
+	method, err := l.method(qctx, conn, address)
+	if err != nil {
+		return stepStats, err
+	}
+
+	request, err := l.buildRequest(method, vm)
+	if err != nil {
+		return stepStats, err
+	}
+
+	md := metadata.MD{}
+
+	for _, item := range l.Metadata {
+		value, err := item.evaluate(ctx, vm)
+		if err != nil {
+			return stepStats, err
+		}
+
+		md.Append(item.Name, value)
+	}
+
+	callStats := &grpcCallStats{}
+
+	qctx = metadata.NewOutgoingContext(qctx, md)
+	qctx = contextWithGrpcCallStats(qctx, callStats)
+
+	start := time.Now()
+
+	messages, err := l.call(qctx, conn, method, request)
+
+	durationRequest := time.Since(start)
+	stepStats.DurationRequest = &durationRequest
+
+	grpcStatus, _ := status.FromError(err)
+
+	stepStats.Code.Scan(grpcStatus.Code().String())
+	stepStats.BytesSent.Scan(callStats.BytesSent())
+	stepStats.BytesReceived.Scan(callStats.BytesReceived())
+	stepStats.MessagesSent.Scan(int64(1))
+	stepStats.MessagesReceived.Scan(callStats.MessagesReceived())
+
+	if ctx.Err() != nil {
+		return stepStats, ctx.Err()
+	}
+
+	if err != nil && !l.hasCodeAssertion() {
+		return stepStats, fmt.Errorf("grpc call failed: %s", err)
+	}
+
+	err = l.assignResponseObject(ctx, grpcStatus, messages, vm)
+	if err != nil {
+		return stepStats, err
+	}
+
+	for _, assertion := range l.Assertions {
+		err = assertion.Verify(ctx, grpcStatus.Code(), vm)
+		if err != nil {
+			return stepStats, err
+		}
+	}
+
+	return stepStats, nil
+}
+
+var _ IRunnableStep = (*LoadTestStepGrpc)(nil)
@@ -4,8 +4,12 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
-	"net/http/httputil"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/indece-official/loadtest/src/report"
@@ -28,15 +32,48 @@ const (
 	HttpMethodPatch   HttpMethod = "PATCH"
 )
 
+// HttpCodeTLSError is recorded as code in the stats if the tls handshake failed
+const HttpCodeTLSError = "tls_error"
+
+// defaultMaxResponseBodySize is the number of bytes of the response body captured for assertions
+const defaultMaxResponseBodySize = 1 << 20
+
 type HttpHeader struct {
 	Name  string                 `yaml:"name"`
 	Value null.String            `yaml:"value"`
 	Expr  ExecutableStringOrNull `yaml:"expr"`
 }
 
-type HttpBody struct {
-	Value null.String            `yaml:"value"`
-	Expr  ExecutableStringOrNull `yaml:"expr"`
+// evaluate returns the interpolated value or the result of the expression
+func (h *HttpHeader) evaluate(ctx context.Context, vm *otto.Otto) (string, error) {
+	if h.Name == "" {
+		return "", fmt.Errorf("header item must have a name")
+	}
+
+	if !h.Value.Valid && !h.Expr.Valid {
+		return "", fmt.Errorf("header '%s' must have a child of 'value' | 'expr'", h.Name)
+	}
+
+	if h.Value.Valid {
+		value, err := interpolateString(vm, h.Value.String)
+		if err != nil {
+			return "", fmt.Errorf("error in value of header '%s': %s", h.Name, err)
+		}
+
+		return value, nil
+	}
+
+	val, err := h.Expr.Execute(ctx, vm)
+	if err != nil {
+		return "", fmt.Errorf("error executing 'expr' for header '%s': %s", h.Name, err)
+	}
+
+	strVal, err := val.ToString()
+	if err != nil {
+		return "", fmt.Errorf("'expr' for header '%s' must return a string: %s", h.Name, err)
+	}
+
+	return strVal, nil
 }
 
 type HttpAssertion struct {
@@ -46,15 +83,47 @@ type HttpAssertion struct {
 	ContentType   null.String            `yaml:"contenttype"`
 	MinBodyLength null.Int               `yaml:"min_body_length"`
 	MaxBodyLength null.Int               `yaml:"max_body_length"`
+	BodyContains  null.String            `yaml:"body_contains"`
 	Expr          ExecutableStringOrNull `yaml:"expr"`
 }
 
-func (h *HttpAssertion) Verify(resp *http.Response, bodyLength int64, vm *otto.Otto) error {
-	name := ""
-	if h.Name.Valid {
-		name = fmt.Sprintf("'%s' ", h.Name.String)
+// assertionName returns the quoted name of an assertion for error messages
+func assertionName(name null.String) string {
+	if !name.Valid {
+		return ""
+	}
+
+	return fmt.Sprintf("'%s' ", name.String)
+}
+
+// verifyExprAssertion executes the 'expr' of an assertion, which must return true
+func verifyExprAssertion(ctx context.Context, name null.String, expr ExecutableStringOrNull, vm *otto.Otto) error {
+	if !expr.Valid {
+		return nil
+	}
+
+	prefix := assertionName(name)
+
+	val, err := expr.Execute(ctx, vm)
+	if err != nil {
+		return fmt.Errorf("error executing 'expr' for assertion %s: %s", prefix, err)
 	}
 
+	valBool, err := val.ToBoolean()
+	if err != nil {
+		return fmt.Errorf("'expr' of assertion %smust return a boolean: %s", prefix, err)
+	}
+
+	if !valBool {
+		return fmt.Errorf("assertion %sfailed", prefix)
+	}
+
+	return nil
+}
+
+func (h *HttpAssertion) Verify(ctx context.Context, resp *http.Response, bodyLength int64, body []byte, vm *otto.Otto) error {
+	name := assertionName(h.Name)
+
 	if h.Status.Valid && resp.Status != h.Status.String {
 		return fmt.Errorf("assertion %son http response status failed: expected '%s', got '%s'", name, h.Status.String, resp.Status)
 	}
@@ -75,33 +144,36 @@ func (h *HttpAssertion) Verify(resp *http.Response, bodyLength int64, vm *otto.O
 		return fmt.Errorf("assertion %son http response body length failed: expected <= %d, got %d", name, h.MaxBodyLength.Int64, bodyLength)
 	}
 
-	if h.Expr.Valid {
-		val, err := h.Expr.Execute(vm)
-		if err != nil {
-			return fmt.Errorf("error executing 'expr' for asserion %s: %s", name, err)
-		}
-
-		valBool, err := val.ToBoolean()
-		if err != nil {
-			return fmt.Errorf("'expr' of assertion %smust return a boolean: %s", name, err)
-		}
-
-		if !valBool {
-			return fmt.Errorf("assertion %sfailed", name)
-		}
+	if h.BodyContains.Valid && !strings.Contains(string(body), h.BodyContains.String) {
+		return fmt.Errorf("assertion %son http response body failed: expected to contain '%s'", name, h.BodyContains.String)
 	}
 
-	return nil
+	return verifyExprAssertion(ctx, h.Name, h.Expr, vm)
 }
 
 type LoadTestStepHttp struct {
-	URL         null.String            `yaml:"url"`
-	URLExpr     ExecutableStringOrNull `yaml:"url_expr"`
-	Method      HttpMethod             `yaml:"method"`
-	RequestBody *HttpBody              `yaml:"request_body"`
-	Headers     []HttpHeader           `yaml:"headers"`
-	Timeout     null.String            `yaml:"timeout"`
-	Assertions  []HttpAssertion        `yaml:"assertions"`
+	URL                 null.String            `yaml:"url"`
+	URLExpr             ExecutableStringOrNull `yaml:"url_expr"`
+	Method              HttpMethod             `yaml:"method"`
+	RequestBody         *HttpBody              `yaml:"request_body"`
+	Headers             []HttpHeader           `yaml:"headers"`
+	Query               map[string]string      `yaml:"query"`
+	Auth                *HttpAuth              `yaml:"auth"`
+	AcceptEncoding      null.String            `yaml:"accept_encoding"`
+	DiscardResponseBody null.Bool              `yaml:"discard_response_body"`
+	MaxResponseBodySize null.Int               `yaml:"max_response_body_size"`
+	Timeout             null.String            `yaml:"timeout"`
+	Assertions          []HttpAssertion        `yaml:"assertions"`
+}
+
+func (l *LoadTestStepHttp) hasHeader(name string) bool {
+	for _, header := range l.Headers {
+		if http.CanonicalHeaderKey(header.Name) == http.CanonicalHeaderKey(name) {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (l *LoadTestStepHttp) Validate() error {
@@ -113,82 +185,137 @@ func (l *LoadTestStepHttp) Validate() error {
 		return fmt.Errorf("'url' must not be empty")
 	}
 
-	return nil
-}
-
-func (l *LoadTestStepHttp) assignResponseObject(resp *http.Response, vm *otto.Otto) error {
-	mutexVm.Lock()
-	defer mutexVm.Unlock()
+	if l.RequestBody != nil {
+		err := l.RequestBody.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid request_body: %s", err)
+		}
+	}
 
-	vmObject, err := vm.Object(`response = {}`)
-	if err != nil {
-		return fmt.Errorf("error creating response object: %s", err)
+	if l.Auth != nil {
+		err := l.Auth.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid auth: %s", err)
+		}
 	}
 
-	vmObject.Set("status", resp.Status)
-	vmObject.Set("statuscode", resp.StatusCode)
-	vmHeaderObject, err := vm.Object(`response.header = {}`)
-	if err != nil {
-		return fmt.Errorf("error creating response header object: %s", err)
+	if l.MaxResponseBodySize.Valid && l.MaxResponseBodySize.Int64 < 0 {
+		return fmt.Errorf("'max_response_body_size' must not be negative")
 	}
 
-	for name, values := range resp.Header {
-		if len(values) > 0 {
-			vmHeaderObject.Set(name, values[0])
+	if l.DiscardResponseBody.Valid && l.DiscardResponseBody.Bool {
+		for _, assertion := range l.Assertions {
+			if assertion.BodyContains.Valid {
+				return fmt.Errorf("assertion 'body_contains' can't be used with 'discard_response_body'")
+			}
 		}
 	}
 
 	return nil
 }
 
-func (l *LoadTestStepHttp) Execute(path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error) {
-	var url string
+// buildURL returns the request url with interpolated and escaped placeholders and query parameters
+func (l *LoadTestStepHttp) buildURL(ctx context.Context, vm *otto.Otto) (string, error) {
+	var reqURL string
 
 	if l.URL.Valid {
-		url = l.URL.String
+		var err error
+
+		reqURL, err = interpolateURL(vm, l.URL.String)
+		if err != nil {
+			return "", fmt.Errorf("error in 'url': %s", err)
+		}
 	} else if l.URLExpr.Valid {
-		val, err := l.URLExpr.Execute(vm)
+		val, err := l.URLExpr.Execute(ctx, vm)
 		if err != nil {
-			return nil, fmt.Errorf("error executing 'url_expr': %s", err)
+			return "", fmt.Errorf("error executing 'url_expr': %s", err)
 		}
 
 		if !val.IsString() {
-			return nil, fmt.Errorf("'url_expr' must return a string")
+			return "", fmt.Errorf("'url_expr' must return a string")
 		}
 
-		url, err = val.ToString()
+		reqURL, err = val.ToString()
 		if err != nil {
-			return nil, fmt.Errorf("'url_expr' must return a string: %s", err)
+			return "", fmt.Errorf("'url_expr' must return a string: %s", err)
 		}
 	}
 
-	stepStats := &StepExecutionStats{}
+	if len(l.Query) == 0 {
+		return reqURL, nil
+	}
 
-	reqBody := ""
+	parsedURL, err := url.Parse(reqURL)
+	if err != nil {
+		return "", fmt.Errorf("can't parse url '%s': %s", reqURL, err)
+	}
+
+	query := url.Values{}
 
-	if l.RequestBody != nil && l.RequestBody.Value.Valid {
-		reqBody = l.RequestBody.Value.String
-	} else if l.RequestBody != nil && l.RequestBody.Expr.Valid {
-		val, err := l.RequestBody.Expr.Execute(vm)
+	for name, value := range l.Query {
+		interpolatedValue, err := interpolateString(vm, value)
 		if err != nil {
-			return stepStats, fmt.Errorf("error executing 'expr' for 'request_body': %s", err)
+			return "", fmt.Errorf("error in query parameter '%s': %s", name, err)
 		}
 
-		strVal, err := val.ToString()
-		if err != nil {
-			return stepStats, fmt.Errorf("'expr' for 'request_body' must return a string: %s", err)
+		query.Add(name, interpolatedValue)
+	}
+
+	if parsedURL.RawQuery != "" {
+		parsedURL.RawQuery += "&"
+	}
+
+	parsedURL.RawQuery += query.Encode()
+
+	return parsedURL.String(), nil
+}
+
+func (l *LoadTestStepHttp) assignResponseObject(ctx context.Context, resp *http.Response, body *cappedBuffer, vm *otto.Otto) error {
+	mutexVm.Lock()
+	defer mutexVm.Unlock()
+
+	vmObject, err := vm.Object(`response = {}`)
+	if err != nil {
+		return fmt.Errorf("error creating response object: %s", err)
+	}
+
+	vmObject.Set("status", resp.Status)
+	vmObject.Set("statuscode", resp.StatusCode)
+
+	if body != nil {
+		vmObject.Set("body", body.String())
+		vmObject.Set("body_truncated", body.truncated)
+	}
+
+	vmHeaderObject, err := vm.Object(`response.header = {}`)
+	if err != nil {
+		return fmt.Errorf("error creating response header object: %s", err)
+	}
+
+	for name, values := range resp.Header {
+		if len(values) > 0 {
+			vmHeaderObject.Set(name, values[0])
 		}
+	}
+
+	return nil
+}
 
-		reqBody = strVal
+// roundTrip executes the request and reads the response body, which is returned
+// (if not discarded) together with its length
+func (l *LoadTestStepHttp) roundTrip(ctx context.Context, vm *otto.Otto) (*StepExecutionStats, *http.Response, *cappedBuffer, int64, error) {
+	reqURL, err := l.buildURL(ctx, vm)
+	if err != nil {
+		return nil, nil, nil, 0, err
 	}
 
-	reqBodyBuffer := bytes.NewBufferString(reqBody)
+	stepStats := &StepExecutionStats{}
 
-	qctx := context.Background()
+	qctx := ctx
 	if l.Timeout.Valid {
 		timeout, err := time.ParseDuration(l.Timeout.String)
 		if err != nil {
-			return stepStats, fmt.Errorf("can't parse 'timeout': %s", err)
+			return stepStats, nil, nil, 0, fmt.Errorf("can't parse 'timeout': %s", err)
 		}
 
 		var cancel func()
@@ -197,49 +324,99 @@ func (l *LoadTestStepHttp) Execute(path []string, vm *otto.Otto, runStats *stats
 		defer cancel()
 	}
 
-	req, err := http.NewRequestWithContext(qctx, string(l.Method), url, reqBodyBuffer)
-	if err != nil {
-		return stepStats, fmt.Errorf("can't create http request: %s", err)
+	reqBody := &httpRequestBody{
+		Reader: bytes.NewBufferString(""),
+	}
+
+	if l.RequestBody != nil {
+		var err error
+
+		reqBody, err = l.RequestBody.Build(ctx, vm)
+		if err != nil {
+			return stepStats, nil, nil, 0, err
+		}
 	}
 
-	dumpRequest, err := httputil.DumpRequestOut(req, true)
+	req, err := http.NewRequestWithContext(qctx, string(l.Method), reqURL, reqBody.Reader)
 	if err != nil {
-		return stepStats, fmt.Errorf("can't analyze http request: %s", err)
+		reqBody.close()
+
+		return stepStats, nil, nil, 0, fmt.Errorf("can't create http request: %s", err)
+	}
+
+	req.ContentLength = reqBody.ContentLength
+
+	if reqBody.GetBody != nil {
+		req.GetBody = reqBody.GetBody
 	}
 
-	stepStats.BytesSent.Scan(len(dumpRequest))
+	if reqBody.ContentType != "" && !l.hasHeader("Content-Type") {
+		req.Header.Set("Content-Type", reqBody.ContentType)
+	}
+
+	if reqBody.ContentEncoding != "" && !l.hasHeader("Content-Encoding") {
+		req.Header.Set("Content-Encoding", reqBody.ContentEncoding)
+	}
+
+	// The http client only decompresses responses itself if it set 'Accept-Encoding'
+	if l.AcceptEncoding.Valid {
+		req.Header.Set("Accept-Encoding", l.AcceptEncoding.String)
+	}
 
 	for _, header := range l.Headers {
-		if header.Name == "" {
-			return stepStats, fmt.Errorf("header item must have a name")
-		}
+		value, err := header.evaluate(ctx, vm)
+		if err != nil {
+			reqBody.close()
 
-		if !header.Value.Valid && !header.Expr.Valid {
-			return stepStats, fmt.Errorf("header '%s' must have a child of 'value' | 'expr'", header.Name)
+			return stepStats, nil, nil, 0, err
 		}
 
-		if header.Value.Valid {
-			req.Header.Add(header.Name, header.Value.String)
-		} else if header.Expr.Valid {
-			val, err := header.Expr.Execute(vm)
-			if err != nil {
-				return stepStats, fmt.Errorf("error executing 'expr' for header '%s': %s", header.Name, err)
-			}
+		req.Header.Add(header.Name, value)
+	}
 
-			strVal, err := val.ToString()
-			if err != nil {
-				return stepStats, fmt.Errorf("'expr' for header '%s' must return a string: %s", header.Name, err)
-			}
+	client := http.DefaultClient
+	if virtualUser := virtualUserFromContext(ctx); virtualUser != nil {
+		client = virtualUser.HttpClient()
+	}
 
-			req.Header.Add(header.Name, strVal)
-		}
+	// Only the final request is timed and traced, not the requests for authentication
+	var startReq time.Time
+	var trace *httpTrace
+
+	send := func(req *http.Request) (*http.Response, error) {
+		startReq = time.Now()
+		trace = newHttpTrace()
+
+		return client.Do(req.WithContext(httptrace.WithClientTrace(req.Context(), trace.ClientTrace())))
+	}
+
+	var resp *http.Response
+
+	if l.Auth != nil {
+		resp, err = l.Auth.Do(qctx, client, req, vm, send)
+	} else {
+		resp, err = send(req)
 	}
 
-	startReq := time.Now()
+	// The request wasn't sent if the authentication failed
+	if trace == nil {
+		reqBody.close()
+
+		return stepStats, nil, nil, 0, err
+	}
 
-	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return stepStats, fmt.Errorf("can't execute http request: %s", err)
+		trace.Apply(stepStats, time.Now())
+	}
+
+	if err != nil && trace.TLSHandshakeError() != nil {
+		stepStats.Code.Scan(HttpCodeTLSError)
+
+		return stepStats, nil, nil, 0, fmt.Errorf("tls handshake failed: %s", trace.TLSHandshakeError())
+	}
+
+	if err != nil {
+		return stepStats, nil, nil, 0, fmt.Errorf("can't execute http request: %s", err)
 	}
 	defer resp.Body.Close()
 
@@ -249,29 +426,79 @@ func (l *LoadTestStepHttp) Execute(path []string, vm *otto.Otto, runStats *stats
 	startResp := time.Now()
 
 	stepStats.Code.Scan(fmt.Sprintf("%d", resp.StatusCode))
+	stepStats.Protocol.Scan(resp.Proto)
 
-	dumpResponse, err := httputil.DumpResponse(resp, true)
+	// The body is streamed and counted decompressed, the wire bytes
+	// (headers and maybe compressed body) are counted by the connection.
+	// Only the first bytes are captured for assertions.
+	decodedBody, err := decodeResponseBody(resp, resp.Body)
 	if err != nil {
-		return stepStats, fmt.Errorf("can't analyze http response: %s", err)
+		return stepStats, nil, nil, 0, fmt.Errorf("can't decode http response body: %s", err)
 	}
 
-	stepStats.BytesReceived.Scan(len(dumpResponse))
+	bodyReader := &countingReader{Reader: decodedBody}
+
+	var capturedBody *cappedBuffer
+	var bodyWriter io.Writer = ioutil.Discard
+
+	if !l.DiscardResponseBody.Valid || !l.DiscardResponseBody.Bool {
+		capturedBody = &cappedBuffer{max: defaultMaxResponseBodySize}
+		if l.MaxResponseBodySize.Valid {
+			capturedBody.max = l.MaxResponseBodySize.Int64
+		}
+
+		bodyWriter = capturedBody
+	}
+
+	_, err = io.Copy(bodyWriter, bodyReader)
+
+	trace.Apply(stepStats, time.Now())
+
+	stepStats.BytesReceivedBody.Scan(bodyReader.bytesRead)
+
+	if err != nil {
+		return stepStats, nil, nil, 0, fmt.Errorf("can't read http response body: %s", err)
+	}
 
 	durationResp := time.Since(startResp)
 	stepStats.DurationResponse = &durationResp
 
-	err = l.assignResponseObject(resp, vm)
+	return stepStats, resp, capturedBody, bodyReader.bytesRead, nil
+}
+
+// verify assigns the response to the vm and checks the assertions
+func (l *LoadTestStepHttp) verify(ctx context.Context, resp *http.Response, capturedBody *cappedBuffer, bodyLength int64, vm *otto.Otto) error {
+	err := l.assignResponseObject(ctx, resp, capturedBody, vm)
 	if err != nil {
-		return stepStats, fmt.Errorf("can't assign reponse object to vm")
+		return fmt.Errorf("can't assign reponse object to vm")
+	}
+
+	var body []byte
+	if capturedBody != nil {
+		body = capturedBody.Bytes()
 	}
 
 	for _, assertion := range l.Assertions {
-		err = assertion.Verify(resp, int64(len(dumpResponse)), vm)
+		err = assertion.Verify(ctx, resp, bodyLength, body, vm)
 		if err != nil {
-			return stepStats, err
+			return err
 		}
 	}
 
+	return nil
+}
+
+func (l *LoadTestStepHttp) Execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error) {
+	stepStats, resp, capturedBody, bodyLength, err := l.roundTrip(ctx, vm)
+	if err != nil {
+		return stepStats, err
+	}
+
+	err = l.verify(ctx, resp, capturedBody, bodyLength, vm)
+	if err != nil {
+		return stepStats, err
+	}
+
 	return stepStats, nil
 }
 
@@ -0,0 +1,74 @@
+package model
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/indece-official/loadtest/src/report"
+	"github.com/indece-official/loadtest/src/stats"
+	"github.com/robertkrimen/otto"
+)
+
+type LoadTestStepIf struct {
+	Condition ExecutableStringOrNull `yaml:"condition"`
+	Then      []*LoadTestStep        `yaml:"then"`
+	Else      []*LoadTestStep        `yaml:"else"`
+}
+
+func (l *LoadTestStepIf) Validate() error {
+	if !l.Condition.Valid {
+		return fmt.Errorf("'condition' must not be empty")
+	}
+
+	if len(l.Then) == 0 && len(l.Else) == 0 {
+		return fmt.Errorf("if must contain at least one step in 'then' | 'else'")
+	}
+
+	for i, step := range l.Then {
+		err := step.Validate()
+		if err != nil {
+			return fmt.Errorf("error in step %d of 'then': %s", i+1, err)
+		}
+	}
+
+	for i, step := range l.Else {
+		err := step.Validate()
+		if err != nil {
+			return fmt.Errorf("error in step %d of 'else': %s", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+func (l *LoadTestStepIf) Execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error) {
+	val, err := l.Condition.Execute(ctx, vm)
+	if err != nil {
+		return nil, fmt.Errorf("error executing 'condition': %s", err)
+	}
+
+	boolVal, err := val.ToBoolean()
+	if err != nil {
+		return nil, fmt.Errorf("'condition' must return a boolean")
+	}
+
+	stepStats := &StepExecutionStats{}
+
+	if boolVal {
+		stepStats.DurationPauseNested, err = executeSteps(ctx, l.Then, append(path, "then"), vm, runStats, report)
+		if err != nil {
+			return stepStats, fmt.Errorf("'then' of if failed: %w", err)
+		}
+
+		return stepStats, nil
+	}
+
+	stepStats.DurationPauseNested, err = executeSteps(ctx, l.Else, append(path, "else"), vm, runStats, report)
+	if err != nil {
+		return stepStats, fmt.Errorf("'else' of if failed: %w", err)
+	}
+
+	return stepStats, nil
+}
+
+var _ IRunnableStep = (*LoadTestStepIf)(nil)
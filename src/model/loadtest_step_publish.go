@@ -0,0 +1,182 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/indece-official/loadtest/src/report"
+	"github.com/indece-official/loadtest/src/stats"
+	"github.com/robertkrimen/otto"
+	"gopkg.in/guregu/null.v4"
+)
+
+type LoadTestStepPublish struct {
+	Broker      null.String            `yaml:"broker"`
+	Destination string                 `yaml:"destination"`
+	Body        null.String            `yaml:"body"`
+	JSON        interface{}            `yaml:"json"`
+	Expr        ExecutableStringOrNull `yaml:"expr"`
+	Headers     []HttpHeader           `yaml:"headers"`
+	Count       null.Int               `yaml:"count"`
+}
+
+func (l *LoadTestStepPublish) Validate() error {
+	if l.Destination == "" {
+		return fmt.Errorf("missing 'destination'")
+	}
+
+	count := 0
+
+	for _, isSet := range []bool{
+		l.Body.Valid,
+		l.JSON != nil,
+		l.Expr.Valid,
+	} {
+		if isSet {
+			count++
+		}
+	}
+
+	if count > 1 {
+		return fmt.Errorf("publish must contain only one of 'body' | 'json' | 'expr'")
+	}
+
+	if l.Count.Valid && l.Count.Int64 < 1 {
+		return fmt.Errorf("'count' must be greater than 0")
+	}
+
+	return nil
+}
+
+func (l *LoadTestStepPublish) buildPayload(ctx context.Context, vm *otto.Otto) ([]byte, error) {
+	switch {
+	case l.Body.Valid:
+		body, err := interpolateString(vm, l.Body.String)
+		if err != nil {
+			return nil, fmt.Errorf("error in 'body': %s", err)
+		}
+
+		return []byte(body), nil
+	case l.JSON != nil:
+		value, err := interpolateValue(vm, l.JSON)
+		if err != nil {
+			return nil, fmt.Errorf("error in 'json': %s", err)
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("can't encode 'json': %s", err)
+		}
+
+		return data, nil
+	case l.Expr.Valid:
+		val, err := l.Expr.Execute(ctx, vm)
+		if err != nil {
+			return nil, fmt.Errorf("error executing 'expr': %s", err)
+		}
+
+		strVal, err := val.ToString()
+		if err != nil {
+			return nil, fmt.Errorf("'expr' must return a string: %s", err)
+		}
+
+		return []byte(strVal), nil
+	default:
+		return []byte{}, nil
+	}
+}
+
+func (l *LoadTestStepPublish) buildMessage(ctx context.Context, vm *otto.Otto) (*brokerMessage, error) {
+	payload, err := l.buildPayload(ctx, vm)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &brokerMessage{
+		Headers: map[string]string{},
+		Payload: payload,
+	}
+
+	for _, header := range l.Headers {
+		value, err := header.evaluate(ctx, vm)
+		if err != nil {
+			return nil, err
+		}
+
+		message.Headers[header.Name] = value
+	}
+
+	return message, nil
+}
+
+func (l *LoadTestStepPublish) Execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error) {
+	stepStats := &StepExecutionStats{}
+
+	brokers := messageBrokersFromContext(ctx)
+	if brokers == nil {
+		return stepStats, fmt.Errorf("publish steps must run within a load test")
+	}
+
+	name := defaultMessageBroker
+	if l.Broker.Valid {
+		name = l.Broker.String
+	}
+
+	broker, err := brokers.broker(ctx, name, vm)
+	if err != nil {
+		return stepStats, err
+	}
+
+	destination, err := interpolateString(vm, l.Destination)
+	if err != nil {
+		return stepStats, fmt.Errorf("error in 'destination': %s", err)
+	}
+
+	count := int64(1)
+	if l.Count.Valid {
+		count = l.Count.Int64
+	}
+
+	var messagesSent int64
+	var bytesSent int64
+
+	start := time.Now()
+
+	for i := int64(0); i < count; i++ {
+		message, err := l.buildMessage(ctx, vm)
+		if err != nil {
+			return stepStats, err
+		}
+
+		brokers.tracker.stamp(message)
+
+		err = broker.Publish(ctx, destination, message)
+		if err != nil {
+			brokers.tracker.discard(message)
+
+			return stepStats, fmt.Errorf("can't publish message %d: %s", i+1, err)
+		}
+
+		messagesSent++
+		bytesSent += int64(len(message.Payload))
+
+		stepStats.MessagesSent.Scan(messagesSent)
+		stepStats.BytesSent.Scan(bytesSent)
+	}
+
+	// Flush once per step, so the throughput isn't limited by a round trip per message
+	err = broker.Flush(ctx)
+
+	durationRequest := time.Since(start)
+	stepStats.DurationRequest = &durationRequest
+
+	if err != nil {
+		return stepStats, fmt.Errorf("can't flush published messages: %s", err)
+	}
+
+	return stepStats, nil
+}
+
+var _ IRunnableStep = (*LoadTestStepPublish)(nil)
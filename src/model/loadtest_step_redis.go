@@ -0,0 +1,308 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/indece-official/loadtest/src/report"
+	"github.com/indece-official/loadtest/src/stats"
+	"github.com/redis/go-redis/v9"
+	"github.com/robertkrimen/otto"
+	"gopkg.in/guregu/null.v4"
+)
+
+// redisPipelineOperation is recorded in the stats for a pipeline instead of its commands
+const redisPipelineOperation = "PIPELINE"
+
+type RedisAssertion struct {
+	Name null.String            `yaml:"name"`
+	Expr ExecutableStringOrNull `yaml:"expr"`
+}
+
+type LoadTestStepRedis struct {
+	Address    string           `yaml:"address"`
+	Username   null.String      `yaml:"username"`
+	Password   null.String      `yaml:"password"`
+	DB         null.Int         `yaml:"db"`
+	TLS        *HttpTLSConfig   `yaml:"tls"`
+	Command    []interface{}    `yaml:"command"`
+	Commands   [][]interface{}  `yaml:"commands"`
+	Pipeline   null.Bool        `yaml:"pipeline"`
+	Timeout    null.String      `yaml:"timeout"`
+	Assertions []RedisAssertion `yaml:"assertions"`
+}
+
+func (r *RedisAssertion) Verify(ctx context.Context, vm *otto.Otto) error {
+	return verifyExprAssertion(ctx, r.Name, r.Expr, vm)
+}
+
+// redisReply converts a reply so it can be encoded as json
+func redisReply(value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case []interface{}:
+		result := []interface{}{}
+		for _, item := range typedValue {
+			result = append(result, redisReply(item))
+		}
+
+		return result
+	case map[interface{}]interface{}:
+		result := map[string]interface{}{}
+		for key, item := range typedValue {
+			result[fmt.Sprintf("%v", key)] = redisReply(item)
+		}
+
+		return result
+	default:
+		return typedValue
+	}
+}
+
+func (l *LoadTestStepRedis) Validate() error {
+	if l.Address == "" {
+		return fmt.Errorf("missing 'address'")
+	}
+
+	if (len(l.Command) == 0) == (len(l.Commands) == 0) {
+		return fmt.Errorf("redis must contain one child of 'command' | 'commands'")
+	}
+
+	for i, command := range l.Commands {
+		if len(command) == 0 {
+			return fmt.Errorf("command %d must not be empty", i+1)
+		}
+	}
+
+	if l.TLS != nil {
+		err := l.TLS.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid tls: %s", err)
+		}
+	}
+
+	if l.Timeout.Valid {
+		_, err := parseDurationOrNull("timeout", l.Timeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// client returns the redis client of the virtual user, each virtual user uses a single connection
+func (l *LoadTestStepRedis) client(ctx context.Context, virtualUser *VirtualUser, address string, vm *otto.Otto) (*redis.Client, error) {
+	key := fmt.Sprintf("redis:%s/%d/%s", address, l.DB.Int64, l.Username.String)
+	if l.TLS != nil {
+		key += ":tls"
+	}
+
+	if client, ok := virtualUser.connection(key).(*redis.Client); ok {
+		return client, nil
+	}
+
+	// The step timeout is passed as context deadline, which go-redis ignores by default
+	options := &redis.Options{
+		Addr:                  address,
+		DB:                    int(l.DB.Int64),
+		PoolSize:              1,
+		ContextTimeoutEnabled: true,
+	}
+
+	if l.Username.Valid {
+		username, err := interpolateString(vm, l.Username.String)
+		if err != nil {
+			return nil, fmt.Errorf("error in 'username': %s", err)
+		}
+
+		options.Username = username
+	}
+
+	if l.Password.Valid {
+		password, err := interpolateString(vm, l.Password.String)
+		if err != nil {
+			return nil, fmt.Errorf("error in 'password': %s", err)
+		}
+
+		options.Password = password
+	}
+
+	if l.TLS != nil {
+		tlsConfig, err := l.TLS.NewTLSConfig(ctx, vm)
+		if err != nil {
+			return nil, err
+		}
+
+		options.TLSConfig = tlsConfig
+	}
+
+	client := redis.NewClient(options)
+
+	virtualUser.setConnection(key, client)
+
+	return client, nil
+}
+
+func (l *LoadTestStepRedis) buildCommands(vm *otto.Otto) ([][]interface{}, error) {
+	commands := l.Commands
+	if len(l.Command) > 0 {
+		commands = [][]interface{}{l.Command}
+	}
+
+	result := [][]interface{}{}
+
+	for i, command := range commands {
+		args := []interface{}{}
+
+		for _, arg := range command {
+			value, err := interpolateValue(vm, arg)
+			if err != nil {
+				return nil, fmt.Errorf("error in command %d: %s", i+1, err)
+			}
+
+			args = append(args, value)
+		}
+
+		result = append(result, args)
+	}
+
+	return result, nil
+}
+
+// run executes the commands one after another or as a pipeline,
+// pipelined commands all share the duration of the pipeline
+func (l *LoadTestStepRedis) run(ctx context.Context, client *redis.Client, commands [][]interface{}) ([]*redis.Cmd, []*stats.StepOperation, error) {
+	cmds := []*redis.Cmd{}
+	operations := []*stats.StepOperation{}
+
+	if l.Pipeline.Valid && l.Pipeline.Bool {
+		pipeline := client.Pipeline()
+
+		for _, command := range commands {
+			cmds = append(cmds, pipeline.Do(ctx, command...))
+		}
+
+		start := time.Now()
+
+		_, err := pipeline.Exec(ctx)
+
+		// The commands share one round trip, so there is no latency per command
+		operations = append(operations, &stats.StepOperation{
+			Name:     redisPipelineOperation,
+			Duration: time.Since(start),
+		})
+
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return cmds, operations, err
+		}
+
+		return cmds, operations, nil
+	}
+
+	for _, command := range commands {
+		start := time.Now()
+
+		cmd := client.Do(ctx, command...)
+
+		operations = append(operations, &stats.StepOperation{
+			Name:     strings.ToUpper(cmd.Name()),
+			Duration: time.Since(start),
+		})
+
+		cmds = append(cmds, cmd)
+
+		if cmd.Err() != nil && !errors.Is(cmd.Err(), redis.Nil) {
+			return cmds, operations, cmd.Err()
+		}
+	}
+
+	return cmds, operations, nil
+}
+
+// assignResponseObject sets 'response' in the vm, 'reply' holds the reply of the last
+// command and 'replies' the replies of all commands
+func (l *LoadTestStepRedis) assignResponseObject(ctx context.Context, cmds []*redis.Cmd, vm *otto.Otto) error {
+	replies := []interface{}{}
+
+	for _, cmd := range cmds {
+		replies = append(replies, redisReply(cmd.Val()))
+	}
+
+	var reply interface{}
+	if len(replies) > 0 {
+		reply = replies[len(replies)-1]
+	}
+
+	return assignVariable(ctx, vm, "response", map[string]interface{}{
+		"reply":   reply,
+		"replies": replies,
+	})
+}
+
+func (l *LoadTestStepRedis) Execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error) {
+	stepStats := &StepExecutionStats{}
+
+	virtualUser := virtualUserFromContext(ctx)
+	if virtualUser == nil {
+		return stepStats, fmt.Errorf("redis steps must run within a load test")
+	}
+
+	address, err := interpolateString(vm, l.Address)
+	if err != nil {
+		return stepStats, fmt.Errorf("error in 'address': %s", err)
+	}
+
+	client, err := l.client(ctx, virtualUser, address, vm)
+	if err != nil {
+		return stepStats, err
+	}
+
+	commands, err := l.buildCommands(vm)
+	if err != nil {
+		return stepStats, err
+	}
+
+	qctx := ctx
+	if l.Timeout.Valid {
+		timeout, err := parseDurationOrNull("timeout", l.Timeout)
+		if err != nil {
+			return stepStats, err
+		}
+
+		var cancel func()
+
+		qctx, cancel = context.WithTimeout(qctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+
+	cmds, operations, err := l.run(qctx, client, commands)
+
+	durationRequest := time.Since(start)
+	stepStats.DurationRequest = &durationRequest
+	stepStats.Operations = operations
+
+	if err != nil {
+		return stepStats, fmt.Errorf("redis command failed: %s", err)
+	}
+
+	err = l.assignResponseObject(ctx, cmds, vm)
+	if err != nil {
+		return stepStats, err
+	}
+
+	for _, assertion := range l.Assertions {
+		err = assertion.Verify(ctx, vm)
+		if err != nil {
+			return stepStats, err
+		}
+	}
+
+	return stepStats, nil
+}
+
+var _ IRunnableStep = (*LoadTestStepRedis)(nil)
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/indece-official/loadtest/src/report"
@@ -20,8 +21,8 @@ func (l *LoadTestStepExec) Validate() error {
 	return nil
 }
 
-func (l *LoadTestStepExec) Execute(path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error) {
-	_, err := l.Script.Execute(vm)
+func (l *LoadTestStepExec) Execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error) {
+	_, err := l.Script.Execute(ctx, vm)
 	if err != nil {
 		return nil, fmt.Errorf("can't execute script: %s", err)
 	}
@@ -0,0 +1,166 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/indece-official/loadtest/src/report"
+	"github.com/indece-official/loadtest/src/stats"
+	"github.com/indece-official/loadtest/src/utils"
+	"github.com/robertkrimen/otto"
+	"gopkg.in/guregu/null.v4"
+)
+
+type SleepDistribution string
+
+const (
+	SleepDistributionFixed       SleepDistribution = "fixed"
+	SleepDistributionUniform     SleepDistribution = "uniform"
+	SleepDistributionNormal      SleepDistribution = "normal"
+	SleepDistributionExponential SleepDistribution = "exponential"
+)
+
+type LoadTestStepSleep struct {
+	Distribution SleepDistribution `yaml:"distribution"`
+	Duration     null.String       `yaml:"duration"`
+	Min          null.String       `yaml:"min"`
+	Max          null.String       `yaml:"max"`
+	Mean         null.String       `yaml:"mean"`
+	StdDev       null.String       `yaml:"stddev"`
+}
+
+func parseDurationOrNull(name string, value null.String) (time.Duration, error) {
+	if !value.Valid {
+		return 0, fmt.Errorf("missing '%s'", name)
+	}
+
+	duration, err := time.ParseDuration(value.String)
+	if err != nil {
+		return 0, fmt.Errorf("can't parse '%s': %s", name, err)
+	}
+
+	if duration < 0 {
+		return 0, fmt.Errorf("'%s' must not be negative", name)
+	}
+
+	return duration, nil
+}
+
+func (l *LoadTestStepSleep) Validate() error {
+	switch l.Distribution {
+	case "", SleepDistributionFixed:
+		_, err := parseDurationOrNull("duration", l.Duration)
+		if err != nil {
+			return err
+		}
+	case SleepDistributionUniform:
+		min, err := parseDurationOrNull("min", l.Min)
+		if err != nil {
+			return err
+		}
+
+		max, err := parseDurationOrNull("max", l.Max)
+		if err != nil {
+			return err
+		}
+
+		if min > max {
+			return fmt.Errorf("'min' must not be greater than 'max'")
+		}
+	case SleepDistributionNormal:
+		_, err := parseDurationOrNull("mean", l.Mean)
+		if err != nil {
+			return err
+		}
+
+		_, err = parseDurationOrNull("stddev", l.StdDev)
+		if err != nil {
+			return err
+		}
+	case SleepDistributionExponential:
+		_, err := parseDurationOrNull("mean", l.Mean)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported distribution '%s'", l.Distribution)
+	}
+
+	return nil
+}
+
+// NextDuration returns a random sleep duration according to the configured distribution
+func (l *LoadTestStepSleep) NextDuration() (time.Duration, error) {
+	var duration time.Duration
+
+	switch l.Distribution {
+	case "", SleepDistributionFixed:
+		fixed, err := parseDurationOrNull("duration", l.Duration)
+		if err != nil {
+			return 0, err
+		}
+
+		duration = fixed
+	case SleepDistributionUniform:
+		min, err := parseDurationOrNull("min", l.Min)
+		if err != nil {
+			return 0, err
+		}
+
+		max, err := parseDurationOrNull("max", l.Max)
+		if err != nil {
+			return 0, err
+		}
+
+		duration = min + time.Duration(rand.Float64()*float64(max-min))
+	case SleepDistributionNormal:
+		mean, err := parseDurationOrNull("mean", l.Mean)
+		if err != nil {
+			return 0, err
+		}
+
+		stdDev, err := parseDurationOrNull("stddev", l.StdDev)
+		if err != nil {
+			return 0, err
+		}
+
+		duration = mean + time.Duration(rand.NormFloat64()*float64(stdDev))
+	case SleepDistributionExponential:
+		mean, err := parseDurationOrNull("mean", l.Mean)
+		if err != nil {
+			return 0, err
+		}
+
+		duration = time.Duration(rand.ExpFloat64() * float64(mean))
+	default:
+		return 0, fmt.Errorf("unsupported distribution '%s'", l.Distribution)
+	}
+
+	if duration < 0 {
+		duration = 0
+	}
+
+	return duration, nil
+}
+
+func (l *LoadTestStepSleep) Execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error) {
+	duration, err := l.NextDuration()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+
+	err = utils.SleepContext(ctx, duration)
+
+	durationPause := time.Since(start)
+
+	stepStats := &StepExecutionStats{}
+	stepStats.DurationPause = &durationPause
+
+	return stepStats, err
+}
+
+var _ IRunnableStep = (*LoadTestStepSleep)(nil)
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/indece-official/loadtest/src/stats"
+	"github.com/robertkrimen/otto"
+	"gopkg.in/guregu/null.v4"
+)
+
+func TestNestedPauseIsNotPartOfParentLatency(t *testing.T) {
+	sleep := 50 * time.Millisecond
+
+	step := &LoadTestStep{
+		Name: null.StringFrom("loop"),
+		Loop: &LoadTestStepLoop{
+			Count: null.IntFrom(2),
+			Steps: []*LoadTestStep{
+				{
+					Name: null.StringFrom("if"),
+					If: &LoadTestStepIf{
+						Condition: ExecutableStringOrNull{Valid: true, String: "true"},
+						Then: []*LoadTestStep{
+							{
+								Name:  null.StringFrom("sleep"),
+								Sleep: &LoadTestStepSleep{Duration: null.StringFrom(sleep.String())},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	runStats := stats.NewRunStats()
+
+	err := step.Execute(context.Background(), []string{}, otto.New(), runStats, nil)
+	if err != nil {
+		t.Fatalf("step failed: %s", err)
+	}
+
+	runStats.Aggregate()
+
+	for _, name := range []string{"loop", "if"} {
+		for _, execution := range runStats.Steps[name].Executions {
+			if execution.DurationTotal >= sleep {
+				t.Errorf("duration of '%s' = %s contains the nested sleep", name, execution.DurationTotal)
+			}
+
+			if execution.DurationPause != nil && *execution.DurationPause != 0 {
+				t.Errorf("pause of '%s' = %s, the nested sleep must only be recorded once", name, *execution.DurationPause)
+			}
+		}
+	}
+
+	if runStats.DurationPause < 2*sleep || runStats.DurationPause >= 3*sleep {
+		t.Errorf("total pause = %s, expected 2 x %s", runStats.DurationPause, sleep)
+	}
+}
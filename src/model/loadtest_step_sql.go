@@ -0,0 +1,239 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/indece-official/loadtest/src/report"
+	"github.com/indece-official/loadtest/src/stats"
+	"github.com/robertkrimen/otto"
+	"gopkg.in/guregu/null.v4"
+)
+
+// defaultSqlMaxRows is the number of rows exposed to the vm, all rows are counted
+const defaultSqlMaxRows = 1000
+
+type SqlAssertion struct {
+	Name null.String            `yaml:"name"`
+	Rows null.Int               `yaml:"rows"`
+	Expr ExecutableStringOrNull `yaml:"expr"`
+}
+
+type LoadTestStepSql struct {
+	Database   null.String    `yaml:"database"`
+	Query      string         `yaml:"query"`
+	Params     []interface{}  `yaml:"params"`
+	Exec       null.Bool      `yaml:"exec"`
+	MaxRows    null.Int       `yaml:"max_rows"`
+	Timeout    null.String    `yaml:"timeout"`
+	Assertions []SqlAssertion `yaml:"assertions"`
+}
+
+// sqlResult is exposed to the vm as 'response'
+type sqlResult struct {
+	Columns      []string                 `json:"columns"`
+	Rows         []map[string]interface{} `json:"rows"`
+	Count        int64                    `json:"count"`
+	RowsAffected int64                    `json:"rows_affected"`
+	LastInsertID int64                    `json:"last_insert_id"`
+}
+
+// Verify checks the assertion, 'rows' is compared to the number of returned or affected rows
+func (s *SqlAssertion) Verify(ctx context.Context, count int64, vm *otto.Otto) error {
+	name := assertionName(s.Name)
+
+	if s.Rows.Valid && s.Rows.Int64 != count {
+		return fmt.Errorf("assertion %son sql rows failed: expected %d, got %d", name, s.Rows.Int64, count)
+	}
+
+	return verifyExprAssertion(ctx, s.Name, s.Expr, vm)
+}
+
+func (l *LoadTestStepSql) Validate() error {
+	if l.Query == "" {
+		return fmt.Errorf("missing 'query'")
+	}
+
+	if l.MaxRows.Valid && l.MaxRows.Int64 < 0 {
+		return fmt.Errorf("'max_rows' must not be negative")
+	}
+
+	if l.Timeout.Valid {
+		_, err := parseDurationOrNull("timeout", l.Timeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l *LoadTestStepSql) buildParams(vm *otto.Otto) ([]interface{}, error) {
+	params := []interface{}{}
+
+	for i, param := range l.Params {
+		value, err := interpolateValue(vm, param)
+		if err != nil {
+			return nil, fmt.Errorf("error in param %d: %s", i+1, err)
+		}
+
+		params = append(params, value)
+	}
+
+	return params, nil
+}
+
+func (l *LoadTestStepSql) exec(ctx context.Context, db *sql.DB, params []interface{}) (*sqlResult, error) {
+	result, err := db.ExecContext(ctx, l.Query, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlResult := &sqlResult{
+		Columns: []string{},
+		Rows:    []map[string]interface{}{},
+	}
+
+	// Not all drivers support both values
+	sqlResult.RowsAffected, _ = result.RowsAffected()
+	sqlResult.LastInsertID, _ = result.LastInsertId()
+	sqlResult.Count = sqlResult.RowsAffected
+
+	return sqlResult, nil
+}
+
+func (l *LoadTestStepSql) query(ctx context.Context, db *sql.DB, params []interface{}) (*sqlResult, error) {
+	rows, err := db.QueryContext(ctx, l.Query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("can't get columns: %s", err)
+	}
+
+	maxRows := int64(defaultSqlMaxRows)
+	if l.MaxRows.Valid {
+		maxRows = l.MaxRows.Int64
+	}
+
+	sqlResult := &sqlResult{
+		Columns: columns,
+		Rows:    []map[string]interface{}{},
+	}
+
+	for rows.Next() {
+		sqlResult.Count++
+
+		if sqlResult.Count > maxRows {
+			continue
+		}
+
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		err = rows.Scan(pointers...)
+		if err != nil {
+			return nil, fmt.Errorf("can't scan row: %s", err)
+		}
+
+		row := map[string]interface{}{}
+		for i, column := range columns {
+			row[column] = sqlValue(values[i])
+		}
+
+		sqlResult.Rows = append(sqlResult.Rows, row)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("can't read rows: %s", err)
+	}
+
+	return sqlResult, nil
+}
+
+func (l *LoadTestStepSql) assignResponseObject(ctx context.Context, result *sqlResult, vm *otto.Otto) error {
+	return assignVariable(ctx, vm, "response", result)
+}
+
+func (l *LoadTestStepSql) Execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error) {
+	stepStats := &StepExecutionStats{}
+
+	databases := sqlDatabasesFromContext(ctx)
+	if databases == nil {
+		return stepStats, fmt.Errorf("sql steps must run within a load test")
+	}
+
+	name := defaultSqlDatabase
+	if l.Database.Valid {
+		name = l.Database.String
+	}
+
+	db, config, err := databases.database(name, vm)
+	if err != nil {
+		return stepStats, err
+	}
+
+	stepStats.Protocol.Scan(config.Driver)
+
+	params, err := l.buildParams(vm)
+	if err != nil {
+		return stepStats, err
+	}
+
+	qctx := ctx
+	if l.Timeout.Valid {
+		timeout, err := parseDurationOrNull("timeout", l.Timeout)
+		if err != nil {
+			return stepStats, err
+		}
+
+		var cancel func()
+
+		qctx, cancel = context.WithTimeout(qctx, timeout)
+		defer cancel()
+	}
+
+	var result *sqlResult
+
+	start := time.Now()
+
+	if l.Exec.Valid && l.Exec.Bool {
+		result, err = l.exec(qctx, db, params)
+	} else {
+		result, err = l.query(qctx, db, params)
+	}
+
+	durationRequest := time.Since(start)
+	stepStats.DurationRequest = &durationRequest
+
+	if err != nil {
+		return stepStats, fmt.Errorf("sql query failed: %s", err)
+	}
+
+	stepStats.Rows.Scan(result.Count)
+
+	err = l.assignResponseObject(ctx, result, vm)
+	if err != nil {
+		return stepStats, err
+	}
+
+	for _, assertion := range l.Assertions {
+		err = assertion.Verify(ctx, result.Count, vm)
+		if err != nil {
+			return stepStats, err
+		}
+	}
+
+	return stepStats, nil
+}
+
+var _ IRunnableStep = (*LoadTestStepSql)(nil)
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/robertkrimen/otto"
+	"gopkg.in/guregu/null.v4"
+)
+
+func newSqlTestContext(t *testing.T) context.Context {
+	databases := newSqlDatabases(map[string]*SqlDatabaseConfig{
+		defaultSqlDatabase: {
+			Driver:       "sqlite",
+			DSN:          filepath.Join(t.TempDir(), "test.db"),
+			MaxOpenConns: null.IntFrom(1),
+		},
+	})
+	t.Cleanup(databases.Close)
+
+	return contextWithSqlDatabases(context.Background(), databases)
+}
+
+func TestLoadTestStepSql(t *testing.T) {
+	ctx := newSqlTestContext(t)
+	vm := otto.New()
+
+	steps := []*LoadTestStepSql{
+		{
+			Query: "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT)",
+			Exec:  null.BoolFrom(true),
+		},
+		{
+			Query:  "INSERT INTO users (name) VALUES (?), (?), (?)",
+			Params: []interface{}{"a", "b", "c"},
+			Exec:   null.BoolFrom(true),
+			Assertions: []SqlAssertion{
+				{Rows: null.IntFrom(3)},
+				{Expr: ExecutableStringOrNull{Valid: true, String: "response.last_insert_id === 3"}},
+			},
+		},
+		{
+			Query:   "SELECT id, name FROM users ORDER BY id",
+			MaxRows: null.IntFrom(2),
+			Assertions: []SqlAssertion{
+				{Rows: null.IntFrom(3)},
+				{Expr: ExecutableStringOrNull{Valid: true, String: "response.rows.length === 2 && response.rows[1].name === 'b'"}},
+				{Expr: ExecutableStringOrNull{Valid: true, String: "response.columns.join() === 'id,name'"}},
+			},
+		},
+	}
+
+	for i, step := range steps {
+		stepStats, err := step.Execute(ctx, nil, vm, nil, nil)
+		if err != nil {
+			t.Fatalf("step %d failed: %s", i+1, err)
+		}
+
+		if stepStats.DurationRequest == nil {
+			t.Errorf("step %d has no request duration", i+1)
+		}
+	}
+
+	step := &LoadTestStepSql{
+		Query:  "SELECT name FROM users WHERE name = ?",
+		Params: []interface{}{"x"},
+		Assertions: []SqlAssertion{
+			{Name: null.StringFrom("one user"), Rows: null.IntFrom(1)},
+		},
+	}
+
+	stepStats, err := step.Execute(ctx, nil, vm, nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "expected 1, got 0") {
+		t.Errorf("expected failed rows assertion, got %v", err)
+	}
+
+	if stepStats.Rows.Int64 != 0 || !stepStats.Rows.Valid {
+		t.Errorf("unexpected rows %v", stepStats.Rows)
+	}
+}
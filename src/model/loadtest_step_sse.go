@@ -0,0 +1,346 @@
+package model
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"time"
+
+	"github.com/indece-official/loadtest/src/report"
+	"github.com/indece-official/loadtest/src/stats"
+	"github.com/robertkrimen/otto"
+	"gopkg.in/guregu/null.v4"
+)
+
+const defaultSSETimeout = 30 * time.Second
+
+type SSEAssertion struct {
+	Name null.String            `yaml:"name"`
+	Expr ExecutableStringOrNull `yaml:"expr"`
+}
+
+// LoadTestStepSSE holds a server-sent events stream open until 'count' events matched 'until'
+// or, with 'duration', for a fixed time or until the server closes it while counting the events
+type LoadTestStepSSE struct {
+	URL         string                 `yaml:"url"`
+	Headers     []HttpHeader           `yaml:"headers"`
+	Auth        *HttpAuth              `yaml:"auth"`
+	LastEventID null.String            `yaml:"last_event_id"`
+	Count       null.Int               `yaml:"count"`
+	Until       ExecutableStringOrNull `yaml:"until"`
+	Duration    null.String            `yaml:"duration"`
+	Timeout     null.String            `yaml:"timeout"`
+	Assertions  []SSEAssertion         `yaml:"assertions"`
+}
+
+type sseEvent struct {
+	ID    string `json:"id"`
+	Event string `json:"event"`
+	Data  string `json:"data"`
+}
+
+// sseReader parses the event stream format
+type sseReader struct {
+	reader *bufio.Reader
+	lastID string
+}
+
+// Next returns the next event, comments and fields other than
+// 'id', 'event' and 'data' are ignored
+func (s *sseReader) Next() (*sseEvent, error) {
+	event := &sseEvent{}
+	data := []string{}
+
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+
+		if line == "" {
+			if len(data) == 0 {
+				event = &sseEvent{}
+
+				continue
+			}
+
+			event.ID = s.lastID
+			event.Data = strings.Join(data, "\n")
+			if event.Event == "" {
+				event.Event = "message"
+			}
+
+			return event, nil
+		}
+
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "event":
+			event.Event = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			s.lastID = value
+		}
+	}
+}
+
+func (s *SSEAssertion) Verify(ctx context.Context, vm *otto.Otto) error {
+	return verifyExprAssertion(ctx, s.Name, s.Expr, vm)
+}
+
+func (l *LoadTestStepSSE) Validate() error {
+	if l.URL == "" {
+		return fmt.Errorf("missing 'url'")
+	}
+
+	if l.Auth != nil {
+		err := l.Auth.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid auth: %s", err)
+		}
+	}
+
+	if l.Count.Valid && l.Count.Int64 < 1 {
+		return fmt.Errorf("'count' must be greater than 0")
+	}
+
+	if l.Duration.Valid {
+		_, err := parseDurationOrNull("duration", l.Duration)
+		if err != nil {
+			return err
+		}
+	}
+
+	if l.Timeout.Valid {
+		_, err := parseDurationOrNull("timeout", l.Timeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// matches sets the received event as variable 'event' and evaluates 'until'
+func (l *LoadTestStepSSE) matches(ctx context.Context, event *sseEvent, vm *otto.Otto) (bool, error) {
+	return matchesUntil(ctx, vm, "event", event, l.Until)
+}
+
+func (l *LoadTestStepSSE) buildRequest(ctx context.Context, vm *otto.Otto) (*http.Request, error) {
+	url, err := interpolateURL(vm, l.URL)
+	if err != nil {
+		return nil, fmt.Errorf("error in 'url': %s", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("can't create http request: %s", err)
+	}
+
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+
+	if l.LastEventID.Valid {
+		lastEventID, err := interpolateString(vm, l.LastEventID.String)
+		if err != nil {
+			return nil, fmt.Errorf("error in 'last_event_id': %s", err)
+		}
+
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	for _, header := range l.Headers {
+		value, err := header.evaluate(ctx, vm)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Add(header.Name, value)
+	}
+
+	return req, nil
+}
+
+func (l *LoadTestStepSSE) Execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error) {
+	stepStats := &StepExecutionStats{}
+
+	timeout := defaultSSETimeout
+	if l.Timeout.Valid {
+		var err error
+
+		timeout, err = parseDurationOrNull("timeout", l.Timeout)
+		if err != nil {
+			return stepStats, err
+		}
+	}
+
+	// With a duration the stream is held until it ends, otherwise until the timeout
+	holdDuration := timeout
+	if l.Duration.Valid {
+		var err error
+
+		holdDuration, err = parseDurationOrNull("duration", l.Duration)
+		if err != nil {
+			return stepStats, err
+		}
+	}
+
+	count := int64(1)
+	if l.Count.Valid {
+		count = l.Count.Int64
+	} else if l.Duration.Valid {
+		count = 0
+	}
+
+	qctx, cancel := context.WithTimeout(ctx, holdDuration)
+	defer cancel()
+
+	req, err := l.buildRequest(qctx, vm)
+	if err != nil {
+		return stepStats, err
+	}
+
+	client := http.DefaultClient
+	if virtualUser := virtualUserFromContext(ctx); virtualUser != nil {
+		client = virtualUser.HttpClient()
+	}
+
+	// Only the final request is timed and traced, not the requests for authentication
+	var start time.Time
+	var trace *httpTrace
+
+	send := func(req *http.Request) (*http.Response, error) {
+		start = time.Now()
+		trace = newHttpTrace()
+
+		return client.Do(req.WithContext(httptrace.WithClientTrace(req.Context(), trace.ClientTrace())))
+	}
+
+	var resp *http.Response
+
+	if l.Auth != nil {
+		resp, err = l.Auth.Do(qctx, client, req, vm, send)
+	} else {
+		resp, err = send(req)
+	}
+
+	if trace == nil {
+		return stepStats, err
+	}
+
+	if err != nil {
+		trace.Apply(stepStats, time.Now())
+
+		return stepStats, fmt.Errorf("can't execute http request: %s", err)
+	}
+	defer resp.Body.Close()
+
+	durationReq := time.Since(start)
+	stepStats.DurationRequest = &durationReq
+
+	stepStats.Code.Scan(fmt.Sprintf("%d", resp.StatusCode))
+	stepStats.Protocol.Scan(resp.Proto)
+
+	if resp.StatusCode != http.StatusOK {
+		trace.Apply(stepStats, time.Now())
+
+		return stepStats, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		trace.Apply(stepStats, time.Now())
+
+		return stepStats, fmt.Errorf("unexpected content type '%s', expected 'text/event-stream'", resp.Header.Get("Content-Type"))
+	}
+
+	bodyReader := &countingReader{Reader: resp.Body}
+	events := &sseReader{reader: bufio.NewReader(bodyReader)}
+
+	var messagesReceived int64
+	var lastEvent time.Time
+
+	matched := int64(0)
+
+	for l.Duration.Valid || matched < count {
+		event, err := events.Next()
+
+		if err != nil {
+			trace.Apply(stepStats, time.Now())
+			stepStats.BytesReceivedBody.Scan(bodyReader.bytesRead)
+
+			if ctx.Err() != nil {
+				return stepStats, ctx.Err()
+			}
+
+			// With 'duration' the end of the stream ends the hold like the timeout
+			if l.Duration.Valid && (qctx.Err() != nil || errors.Is(err, io.EOF)) {
+				break
+			}
+
+			if qctx.Err() != nil {
+				return stepStats, fmt.Errorf("timeout after %s waiting for events (%d of %d received)", timeout, matched, count)
+			}
+
+			if errors.Is(err, io.EOF) {
+				return stepStats, fmt.Errorf("event stream closed (%d of %d events received)", matched, count)
+			}
+
+			return stepStats, fmt.Errorf("can't read event stream: %s", err)
+		}
+
+		now := time.Now()
+
+		if lastEvent.IsZero() {
+			durationFirstEvent := now.Sub(start)
+			stepStats.DurationFirstEvent = &durationFirstEvent
+		} else {
+			stepStats.DurationsBetweenEvents = append(stepStats.DurationsBetweenEvents, now.Sub(lastEvent))
+		}
+
+		lastEvent = now
+
+		messagesReceived++
+		stepStats.MessagesReceived.Scan(messagesReceived)
+
+		isMatch, err := l.matches(ctx, event, vm)
+		if err != nil {
+			return stepStats, err
+		}
+
+		if isMatch {
+			matched++
+		}
+	}
+
+	if matched < count {
+		return stepStats, fmt.Errorf("%d of %d events received within %s", matched, count, holdDuration)
+	}
+
+	trace.Apply(stepStats, time.Now())
+	stepStats.BytesReceivedBody.Scan(bodyReader.bytesRead)
+
+	for _, assertion := range l.Assertions {
+		err = assertion.Verify(ctx, vm)
+		if err != nil {
+			return stepStats, err
+		}
+	}
+
+	return stepStats, nil
+}
+
+var _ IRunnableStep = (*LoadTestStepSSE)(nil)
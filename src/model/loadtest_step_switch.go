@@ -0,0 +1,89 @@
+package model
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/indece-official/loadtest/src/report"
+	"github.com/indece-official/loadtest/src/stats"
+	"github.com/robertkrimen/otto"
+)
+
+type LoadTestStepSwitchCase struct {
+	Value interface{}     `yaml:"value"`
+	Steps []*LoadTestStep `yaml:"steps"`
+}
+
+type LoadTestStepSwitch struct {
+	Expr    ExecutableStringOrNull    `yaml:"expr"`
+	Cases   []*LoadTestStepSwitchCase `yaml:"cases"`
+	Default []*LoadTestStep           `yaml:"default"`
+}
+
+func (l *LoadTestStepSwitch) Validate() error {
+	if !l.Expr.Valid {
+		return fmt.Errorf("'expr' must not be empty")
+	}
+
+	if len(l.Cases) == 0 {
+		return fmt.Errorf("switch must contain at least one case")
+	}
+
+	for i, switchCase := range l.Cases {
+		if switchCase.Value == nil {
+			return fmt.Errorf("case %d must have a 'value'", i+1)
+		}
+
+		for j, step := range switchCase.Steps {
+			err := step.Validate()
+			if err != nil {
+				return fmt.Errorf("error in step %d of case %d: %s", j+1, i+1, err)
+			}
+		}
+	}
+
+	for i, step := range l.Default {
+		err := step.Validate()
+		if err != nil {
+			return fmt.Errorf("error in step %d of 'default': %s", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+func (l *LoadTestStepSwitch) Execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error) {
+	val, err := l.Expr.Execute(ctx, vm)
+	if err != nil {
+		return nil, fmt.Errorf("error executing 'expr': %s", err)
+	}
+
+	strVal, err := val.ToString()
+	if err != nil {
+		return nil, fmt.Errorf("can't convert result of 'expr' to string: %s", err)
+	}
+
+	stepStats := &StepExecutionStats{}
+
+	for i, switchCase := range l.Cases {
+		if fmt.Sprintf("%v", switchCase.Value) != strVal {
+			continue
+		}
+
+		stepStats.DurationPauseNested, err = executeSteps(ctx, switchCase.Steps, append(path, fmt.Sprintf("case%d", i)), vm, runStats, report)
+		if err != nil {
+			return stepStats, fmt.Errorf("case %d of switch failed: %w", i+1, err)
+		}
+
+		return stepStats, nil
+	}
+
+	stepStats.DurationPauseNested, err = executeSteps(ctx, l.Default, append(path, "default"), vm, runStats, report)
+	if err != nil {
+		return stepStats, fmt.Errorf("'default' of switch failed: %w", err)
+	}
+
+	return stepStats, nil
+}
+
+var _ IRunnableStep = (*LoadTestStepSwitch)(nil)
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/indece-official/loadtest/src/report"
+	"github.com/indece-official/loadtest/src/stats"
+	"github.com/robertkrimen/otto"
+)
+
+type LoadTestStepTCP struct {
+	socketStep `yaml:",inline"`
+	TLS        *HttpTLSConfig `yaml:"tls"`
+}
+
+func (l *LoadTestStepTCP) Validate() error {
+	err := l.socketStep.validate()
+	if err != nil {
+		return err
+	}
+
+	if l.TLS != nil {
+		err := l.TLS.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid tls: %s", err)
+		}
+	}
+
+	return nil
+}
+
+func (l *LoadTestStepTCP) dial(vm *otto.Otto) socketDialFunc {
+	return func(ctx context.Context, address string, timeout time.Duration) (net.Conn, error) {
+		dialer := &net.Dialer{Timeout: timeout}
+
+		if l.TLS == nil {
+			return dialer.DialContext(ctx, "tcp", address)
+		}
+
+		tlsConfig, err := l.TLS.NewTLSConfig(ctx, vm)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsDialer := &tls.Dialer{
+			NetDialer: dialer,
+			Config:    tlsConfig,
+		}
+
+		return tlsDialer.DialContext(ctx, "tcp", address)
+	}
+}
+
+func (l *LoadTestStepTCP) Execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error) {
+	var read socketReadFunc
+	if l.Read != nil {
+		read = l.Read.read
+	}
+
+	return l.socketStep.execute(ctx, "tcp", vm, l.dial(vm), read)
+}
+
+var _ IRunnableStep = (*LoadTestStepTCP)(nil)
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/indece-official/loadtest/src/report"
+	"github.com/indece-official/loadtest/src/stats"
+	"github.com/robertkrimen/otto"
+)
+
+// maxUDPDatagramSize is the maximum payload of an udp datagram
+const maxUDPDatagramSize = 65535
+
+type LoadTestStepUDP struct {
+	socketStep `yaml:",inline"`
+}
+
+func (l *LoadTestStepUDP) Validate() error {
+	err := l.socketStep.validate()
+	if err != nil {
+		return err
+	}
+
+	if l.Read != nil && (l.Read.Delimiter.Valid || l.Read.Length.Valid) {
+		return fmt.Errorf("udp reads one datagram and doesn't support 'delimiter' | 'length'")
+	}
+
+	return nil
+}
+
+func (l *LoadTestStepUDP) dial(ctx context.Context, address string, timeout time.Duration) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+
+	return dialer.DialContext(ctx, "udp", address)
+}
+
+// read waits for the next datagram, a timeout is an error as the reply is incomplete
+func (l *LoadTestStepUDP) read(ctx context.Context, connection *socketConnection) ([]byte, bool, error) {
+	deadline := time.Now().Add(l.Read.timeout())
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	err := connection.conn.SetReadDeadline(deadline)
+	if err != nil {
+		return nil, false, fmt.Errorf("can't set read deadline: %s", err)
+	}
+
+	stop := interruptOnCancel(ctx, connection.conn.SetReadDeadline)
+	defer stop()
+
+	data := make([]byte, maxUDPDatagramSize)
+
+	n, err := connection.conn.Read(data)
+	if err != nil {
+		return nil, false, fmt.Errorf("can't read datagram: %s", err)
+	}
+
+	return data[:n], false, nil
+}
+
+func (l *LoadTestStepUDP) Execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error) {
+	return l.socketStep.execute(ctx, "udp", vm, l.dial, l.read)
+}
+
+var _ IRunnableStep = (*LoadTestStepUDP)(nil)
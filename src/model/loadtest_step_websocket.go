@@ -0,0 +1,451 @@
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/indece-official/loadtest/src/report"
+	"github.com/indece-official/loadtest/src/stats"
+	"github.com/robertkrimen/otto"
+	"gopkg.in/guregu/null.v4"
+)
+
+const defaultWebSocketConnection = "default"
+const defaultWebSocketTimeout = 10 * time.Second
+
+// webSocketMessageBuffer is the number of received messages queued until a step reads them
+const webSocketMessageBuffer = 1000
+
+type WebSocketMessage struct {
+	Value  null.String            `yaml:"value"`
+	Expr   ExecutableStringOrNull `yaml:"expr"`
+	JSON   interface{}            `yaml:"json"`
+	Binary null.Bool              `yaml:"binary"`
+}
+
+type WebSocketReceive struct {
+	Until   ExecutableStringOrNull `yaml:"until"`
+	Count   null.Int               `yaml:"count"`
+	Timeout null.String            `yaml:"timeout"`
+}
+
+type LoadTestStepWebSocket struct {
+	Connection     null.String        `yaml:"connection"`
+	URL            null.String        `yaml:"url"`
+	Headers        []HttpHeader       `yaml:"headers"`
+	Subprotocols   []string           `yaml:"subprotocols"`
+	ConnectTimeout null.String        `yaml:"connect_timeout"`
+	Send           []WebSocketMessage `yaml:"send"`
+	Receive        *WebSocketReceive  `yaml:"receive"`
+	Close          null.Bool          `yaml:"close"`
+}
+
+type webSocketMessage struct {
+	messageType int
+	data        []byte
+}
+
+// webSocketConnection is kept open per virtual user across steps,
+// messages are read in the background so they don't get lost between steps
+type webSocketConnection struct {
+	conn      *websocket.Conn
+	messages  chan *webSocketMessage
+	closed    chan struct{}
+	closeOnce sync.Once
+	mutex     sync.Mutex
+	err       error
+	dropped   int64
+}
+
+func (w *webSocketConnection) readLoop() {
+	defer close(w.messages)
+
+	for {
+		messageType, data, err := w.conn.ReadMessage()
+		if err != nil {
+			w.mutex.Lock()
+			w.err = err
+			w.mutex.Unlock()
+
+			return
+		}
+
+		// Messages are dropped if no step reads them, blocking would stop
+		// answering pings and the server would close the connection
+		select {
+		case w.messages <- &webSocketMessage{messageType: messageType, data: data}:
+		case <-w.closed:
+			return
+		default:
+			w.mutex.Lock()
+			w.dropped++
+			w.mutex.Unlock()
+		}
+	}
+}
+
+// takeDropped returns the number of messages dropped since the last call
+func (w *webSocketConnection) takeDropped() int64 {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	dropped := w.dropped
+	w.dropped = 0
+
+	return dropped
+}
+
+// write sends a message, it is aborted after the default timeout or when the context is cancelled
+func (w *webSocketConnection) write(ctx context.Context, messageType int, data []byte) error {
+	err := w.conn.SetWriteDeadline(time.Now().Add(defaultWebSocketTimeout))
+	if err != nil {
+		return err
+	}
+
+	stop := interruptOnCancel(ctx, w.conn.SetWriteDeadline)
+	defer stop()
+
+	return w.conn.WriteMessage(messageType, data)
+}
+
+func (w *webSocketConnection) readError() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	return w.err
+}
+
+func (w *webSocketConnection) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.closed)
+
+		w.conn.WriteControl(
+			websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+			time.Now().Add(time.Second),
+		)
+	})
+
+	return w.conn.Close()
+}
+
+func (w *WebSocketMessage) Validate() error {
+	count := 0
+
+	for _, isSet := range []bool{
+		w.Value.Valid,
+		w.Expr.Valid,
+		w.JSON != nil,
+	} {
+		if isSet {
+			count++
+		}
+	}
+
+	if count != 1 {
+		return fmt.Errorf("message must contain one child of 'value' | 'expr' | 'json'")
+	}
+
+	return nil
+}
+
+// build returns the message type and payload
+func (w *WebSocketMessage) build(ctx context.Context, vm *otto.Otto) (int, []byte, error) {
+	messageType := websocket.TextMessage
+	if w.Binary.Valid && w.Binary.Bool {
+		messageType = websocket.BinaryMessage
+	}
+
+	switch {
+	case w.Value.Valid:
+		value, err := interpolateString(vm, w.Value.String)
+		if err != nil {
+			return 0, nil, fmt.Errorf("error in 'value': %s", err)
+		}
+
+		return messageType, []byte(value), nil
+	case w.Expr.Valid:
+		val, err := w.Expr.Execute(ctx, vm)
+		if err != nil {
+			return 0, nil, fmt.Errorf("error executing 'expr': %s", err)
+		}
+
+		strVal, err := val.ToString()
+		if err != nil {
+			return 0, nil, fmt.Errorf("'expr' must return a string: %s", err)
+		}
+
+		return messageType, []byte(strVal), nil
+	default:
+		value, err := interpolateValue(vm, w.JSON)
+		if err != nil {
+			return 0, nil, fmt.Errorf("error in 'json': %s", err)
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return 0, nil, fmt.Errorf("can't encode 'json': %s", err)
+		}
+
+		return messageType, data, nil
+	}
+}
+
+func (l *LoadTestStepWebSocket) Validate() error {
+	if l.URL.Valid && l.URL.String == "" {
+		return fmt.Errorf("'url' must not be empty")
+	}
+
+	if l.ConnectTimeout.Valid {
+		_, err := parseDurationOrNull("connect_timeout", l.ConnectTimeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, message := range l.Send {
+		err := message.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid message %d in 'send': %s", i+1, err)
+		}
+	}
+
+	if l.Receive != nil {
+		if l.Receive.Count.Valid && l.Receive.Count.Int64 < 1 {
+			return fmt.Errorf("'count' of 'receive' must be greater than 0")
+		}
+
+		if l.Receive.Timeout.Valid {
+			_, err := parseDurationOrNull("timeout", l.Receive.Timeout)
+			if err != nil {
+				return fmt.Errorf("invalid 'receive': %s", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (l *LoadTestStepWebSocket) connectionKey() string {
+	name := defaultWebSocketConnection
+	if l.Connection.Valid {
+		name = l.Connection.String
+	}
+
+	return "websocket:" + name
+}
+
+// connect opens a new connection using the tls, proxy and resolve settings of the virtual user's http client
+func (l *LoadTestStepWebSocket) connect(ctx context.Context, virtualUser *VirtualUser, vm *otto.Otto, stepStats *StepExecutionStats) (*webSocketConnection, error) {
+	url, err := interpolateURL(vm, l.URL.String)
+	if err != nil {
+		return nil, fmt.Errorf("error in 'url': %s", err)
+	}
+
+	header := http.Header{}
+
+	for _, h := range l.Headers {
+		value, err := h.evaluate(ctx, vm)
+		if err != nil {
+			return nil, err
+		}
+
+		header.Add(h.Name, value)
+	}
+
+	transport, err := virtualUser.HttpClientConfig().newTransport(ctx, vm)
+	if err != nil {
+		return nil, fmt.Errorf("can't create transport: %s", err)
+	}
+
+	dialer := &websocket.Dialer{
+		Proxy:            transport.Proxy,
+		NetDialContext:   transport.DialContext,
+		TLSClientConfig:  transport.TLSClientConfig,
+		HandshakeTimeout: defaultWebSocketTimeout,
+		Subprotocols:     l.Subprotocols,
+	}
+
+	if l.ConnectTimeout.Valid {
+		dialer.HandshakeTimeout, err = parseDurationOrNull("connect_timeout", l.ConnectTimeout)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	start := time.Now()
+
+	conn, resp, err := dialer.DialContext(ctx, url, header)
+
+	durationConnect := time.Since(start)
+	stepStats.DurationConnect = &durationConnect
+
+	if resp != nil {
+		stepStats.Code.Scan(fmt.Sprintf("%d", resp.StatusCode))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("can't connect websocket: %s", err)
+	}
+
+	stepStats.Protocol.Scan(conn.Subprotocol())
+
+	connection := &webSocketConnection{
+		conn:     conn,
+		messages: make(chan *webSocketMessage, webSocketMessageBuffer),
+		closed:   make(chan struct{}),
+	}
+
+	go connection.readLoop()
+
+	return connection, nil
+}
+
+// matches sets the received message as variable 'message' and evaluates 'until'
+func (l *LoadTestStepWebSocket) matches(ctx context.Context, message *webSocketMessage, vm *otto.Otto) (bool, error) {
+	return matchesUntil(ctx, vm, "message", string(message.data), l.Receive.Until)
+}
+
+func (l *LoadTestStepWebSocket) Execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error) {
+	stepStats := &StepExecutionStats{}
+
+	virtualUser := virtualUserFromContext(ctx)
+	if virtualUser == nil {
+		return stepStats, fmt.Errorf("websocket steps must run within a load test")
+	}
+
+	key := l.connectionKey()
+
+	connection, _ := virtualUser.connection(key).(*webSocketConnection)
+	if connection == nil {
+		if !l.URL.Valid {
+			return stepStats, fmt.Errorf("websocket connection isn't open and no 'url' is given")
+		}
+
+		var err error
+
+		connection, err = l.connect(ctx, virtualUser, vm, stepStats)
+		if err != nil {
+			return stepStats, err
+		}
+
+		virtualUser.setConnection(key, connection)
+	}
+
+	var bytesSent int64
+	var bytesReceived int64
+	var messagesReceived int64
+	var lastSent time.Time
+
+	for i, message := range l.Send {
+		messageType, data, err := message.build(ctx, vm)
+		if err != nil {
+			return stepStats, fmt.Errorf("error in message %d: %s", i+1, err)
+		}
+
+		lastSent = time.Now()
+
+		err = connection.write(ctx, messageType, data)
+		if err != nil {
+			connection.Close()
+			virtualUser.removeConnection(key)
+
+			if ctx.Err() != nil {
+				return stepStats, ctx.Err()
+			}
+
+			return stepStats, fmt.Errorf("can't send websocket message: %s", err)
+		}
+
+		bytesSent += int64(len(data))
+	}
+
+	if len(l.Send) > 0 {
+		stepStats.MessagesSent.Scan(int64(len(l.Send)))
+		stepStats.BytesSent.Scan(bytesSent)
+	}
+
+	if l.Receive != nil {
+		// Messages dropped while the buffer was full are counted by the next receiving step
+		defer func() {
+			dropped := connection.takeDropped()
+			if dropped > 0 {
+				stepStats.MessagesDropped.Scan(dropped)
+			}
+		}()
+
+		timeout := defaultWebSocketTimeout
+		if l.Receive.Timeout.Valid {
+			var err error
+
+			timeout, err = parseDurationOrNull("timeout", l.Receive.Timeout)
+			if err != nil {
+				return stepStats, err
+			}
+		}
+
+		count := int64(1)
+		if l.Receive.Count.Valid {
+			count = l.Receive.Count.Int64
+		}
+
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		matched := int64(0)
+
+		for matched < count {
+			select {
+			case message, ok := <-connection.messages:
+				if !ok {
+					connection.Close()
+					virtualUser.removeConnection(key)
+
+					return stepStats, fmt.Errorf("websocket connection closed: %s", connection.readError())
+				}
+
+				messagesReceived++
+				bytesReceived += int64(len(message.data))
+
+				stepStats.MessagesReceived.Scan(messagesReceived)
+				stepStats.BytesReceived.Scan(bytesReceived)
+
+				isMatch, err := l.matches(ctx, message, vm)
+				if err != nil {
+					return stepStats, err
+				}
+
+				if isMatch {
+					matched++
+				}
+			case <-timer.C:
+				return stepStats, fmt.Errorf("timeout after %s waiting for websocket message (%d of %d received)", timeout, matched, count)
+			case <-ctx.Done():
+				return stepStats, ctx.Err()
+			}
+		}
+
+		if !lastSent.IsZero() {
+			durationRoundTrip := time.Since(lastSent)
+			stepStats.DurationRoundTrip = &durationRoundTrip
+		}
+	}
+
+	if l.Close.Valid && l.Close.Bool {
+		virtualUser.removeConnection(key)
+
+		err := connection.Close()
+		if err != nil {
+			return stepStats, fmt.Errorf("can't close websocket connection: %s", err)
+		}
+	}
+
+	return stepStats, nil
+}
+
+var _ IRunnableStep = (*LoadTestStepWebSocket)(nil)
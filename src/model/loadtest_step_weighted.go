@@ -0,0 +1,93 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+
+	"github.com/indece-official/loadtest/src/report"
+	"github.com/indece-official/loadtest/src/stats"
+	"github.com/robertkrimen/otto"
+	"gopkg.in/guregu/null.v4"
+)
+
+type LoadTestStepWeightedBranch struct {
+	Name   null.String     `yaml:"name"`
+	Weight float64         `yaml:"weight"`
+	Steps  []*LoadTestStep `yaml:"steps"`
+}
+
+type LoadTestStepWeighted struct {
+	Branches []*LoadTestStepWeightedBranch `yaml:"branches"`
+}
+
+func (l *LoadTestStepWeighted) Validate() error {
+	if len(l.Branches) == 0 {
+		return fmt.Errorf("weighted must contain at least one branch")
+	}
+
+	for i, branch := range l.Branches {
+		if branch.Weight <= 0 {
+			return fmt.Errorf("weight of branch %d must be greater 0", i+1)
+		}
+
+		for j, step := range branch.Steps {
+			err := step.Validate()
+			if err != nil {
+				return fmt.Errorf("error in step %d of branch %d: %s", j+1, i+1, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (l *LoadTestStepWeighted) totalWeight() float64 {
+	total := float64(0)
+
+	for _, branch := range l.Branches {
+		total += branch.Weight
+	}
+
+	return total
+}
+
+// selectBranch picks a random branch according to the configured weights
+func (l *LoadTestStepWeighted) selectBranch() (int, *LoadTestStepWeightedBranch) {
+	value := rand.Float64() * l.totalWeight()
+
+	for i, branch := range l.Branches {
+		if value < branch.Weight {
+			return i, branch
+		}
+
+		value -= branch.Weight
+	}
+
+	// Fallback for rounding errors
+	return len(l.Branches) - 1, l.Branches[len(l.Branches)-1]
+}
+
+func (l *LoadTestStepWeighted) Execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error) {
+	i, branch := l.selectBranch()
+
+	branchName := branch.Name.String
+	if !branch.Name.Valid {
+		branchName = fmt.Sprintf("%d", i)
+	}
+
+	stepStats := &StepExecutionStats{}
+	stepStats.Branch.Scan(branchName)
+	stepStats.BranchWeight.Scan(branch.Weight / l.totalWeight())
+
+	var err error
+
+	stepStats.DurationPauseNested, err = executeSteps(ctx, branch.Steps, path, vm, runStats, report)
+	if err != nil {
+		return stepStats, fmt.Errorf("branch '%s' failed: %w", branchName, err)
+	}
+
+	return stepStats, nil
+}
+
+var _ IRunnableStep = (*LoadTestStepWeighted)(nil)
@@ -0,0 +1,305 @@
+package model
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robertkrimen/otto"
+	"gopkg.in/guregu/null.v4"
+)
+
+const contextKeyMessageBrokers contextKey = "message_brokers"
+
+const defaultMessageBroker = "default"
+
+// Headers added to published messages, so consumers can correlate them and measure the end-to-end latency
+const (
+	messageHeaderID          = "Loadtest-Message-Id"
+	messageHeaderPublishedAt = "Loadtest-Published-At"
+)
+
+// brokerMessage is a message independent of the broker protocol
+type brokerMessage struct {
+	Headers map[string]string
+	Payload []byte
+}
+
+// messageBroker is implemented for each supported broker protocol, published
+// messages may be buffered until Flush is called
+type messageBroker interface {
+	io.Closer
+	Publish(ctx context.Context, destination string, message *brokerMessage) error
+	Flush(ctx context.Context) error
+	Subscribe(ctx context.Context, destination string, group string) (messageSubscription, error)
+}
+
+// messageSubscription receives the messages of a destination until it is closed
+type messageSubscription interface {
+	io.Closer
+	Next(ctx context.Context) (*brokerMessage, error)
+}
+
+type messageBrokerFactory func(ctx context.Context, config *MessageBrokerConfig, vm *otto.Otto) (messageBroker, error)
+
+// messageBrokerFactories contains the supported values of 'type', new protocols are registered here
+var messageBrokerFactories = map[string]messageBrokerFactory{
+	"nats": newNatsMessageBroker,
+}
+
+// MessageBrokerConfig defines a broker connection shared by all virtual users of a load test
+type MessageBrokerConfig struct {
+	Type     string         `yaml:"type"`
+	URL      string         `yaml:"url"`
+	Username null.String    `yaml:"username"`
+	Password null.String    `yaml:"password"`
+	Token    null.String    `yaml:"token"`
+	TLS      *HttpTLSConfig `yaml:"tls"`
+}
+
+func (m *MessageBrokerConfig) Validate() error {
+	if _, ok := messageBrokerFactories[m.Type]; !ok {
+		types := []string{}
+		for brokerType := range messageBrokerFactories {
+			types = append(types, brokerType)
+		}
+
+		sort.Strings(types)
+
+		return fmt.Errorf("invalid type '%s', must be one of '%s'", m.Type, strings.Join(types, "' | '"))
+	}
+
+	if m.URL == "" {
+		return fmt.Errorf("missing 'url'")
+	}
+
+	if m.TLS != nil {
+		err := m.TLS.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid tls: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// messageTrackerTTL is the time after which a published message which wasn't consumed is counted as lost
+const messageTrackerTTL = time.Minute
+
+// messageTracker correlates consumed messages with the messages published by this run,
+// each published message is expected to be consumed once (e.g. by a single subscriber
+// or a queue group), further copies are counted as duplicates. Messages are only tracked
+// once a consumer subscribed, so producer-only tests don't keep them.
+type messageTracker struct {
+	runID   string
+	counter uint64
+	mutex   sync.Mutex
+	pending map[uint64]time.Time
+	// queue contains the counters of tracked messages in publish order for expiring them
+	queue []uint64
+	// trackedFrom is the counter of the first tracked message, 0 until a consumer subscribed
+	trackedFrom uint64
+	// expiredUpTo is the counter up to which messages are no longer tracked
+	expiredUpTo uint64
+	expired     int64
+}
+
+// subscribe starts tracking the messages published from now on
+func (m *messageTracker) subscribe() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.trackedFrom == 0 {
+		m.trackedFrom = m.counter + 1
+	}
+}
+
+// expire counts the messages which weren't consumed within messageTrackerTTL as lost,
+// the caller must hold the mutex
+func (m *messageTracker) expire(now time.Time) {
+	for len(m.queue) > 0 {
+		counter := m.queue[0]
+
+		publishedAt, ok := m.pending[counter]
+		if ok && now.Sub(publishedAt) < messageTrackerTTL {
+			return
+		}
+
+		if ok {
+			delete(m.pending, counter)
+			m.expired++
+		}
+
+		m.expiredUpTo = counter
+		m.queue = m.queue[1:]
+	}
+}
+
+// stamp adds the correlation id to the message and waits for it to be consumed
+func (m *messageTracker) stamp(message *brokerMessage) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.counter++
+	publishedAt := time.Now()
+
+	message.Headers[messageHeaderID] = fmt.Sprintf("%s-%d", m.runID, m.counter)
+	message.Headers[messageHeaderPublishedAt] = strconv.FormatInt(publishedAt.UnixNano(), 10)
+
+	if m.trackedFrom == 0 {
+		return
+	}
+
+	m.expire(publishedAt)
+
+	m.pending[m.counter] = publishedAt
+	m.queue = append(m.queue, m.counter)
+}
+
+// messageCounter returns the counter of a message published by this run
+func (m *messageTracker) messageCounter(message *brokerMessage) (uint64, bool) {
+	id := message.Headers[messageHeaderID]
+	if !strings.HasPrefix(id, m.runID+"-") {
+		return 0, false
+	}
+
+	counter, err := strconv.ParseUint(strings.TrimPrefix(id, m.runID+"-"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return counter, true
+}
+
+// discard stops waiting for a message which couldn't be published
+func (m *messageTracker) discard(message *brokerMessage) {
+	counter, ok := m.messageCounter(message)
+	if !ok {
+		return
+	}
+
+	m.mutex.Lock()
+	delete(m.pending, counter)
+	m.mutex.Unlock()
+}
+
+// consume matches the message with its publish and returns the end-to-end latency,
+// messages of other publishers or published before tracking started have no latency,
+// already consumed ones are duplicates
+func (m *messageTracker) consume(message *brokerMessage) (latency *time.Duration, duplicate bool) {
+	counter, ok := m.messageCounter(message)
+	if !ok {
+		return nil, false
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.trackedFrom == 0 || counter < m.trackedFrom || counter <= m.expiredUpTo {
+		return nil, false
+	}
+
+	publishedAt, ok := m.pending[counter]
+	if !ok {
+		return nil, true
+	}
+
+	delete(m.pending, counter)
+
+	duration := time.Since(publishedAt)
+
+	return &duration, false
+}
+
+// lost returns the number of tracked messages which weren't consumed,
+// it is only valid if a consumer subscribed at all
+func (m *messageTracker) lost() null.Int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.trackedFrom == 0 {
+		return null.Int{}
+	}
+
+	return null.IntFrom(m.expired + int64(len(m.pending)))
+}
+
+func newMessageTracker() *messageTracker {
+	data := make([]byte, 8)
+	rand.Read(data)
+
+	return &messageTracker{
+		runID:   hex.EncodeToString(data),
+		pending: map[uint64]time.Time{},
+	}
+}
+
+// messageBrokers holds the broker connections of a load test, which are opened on first use
+type messageBrokers struct {
+	configs map[string]*MessageBrokerConfig
+	tracker *messageTracker
+	mutex   sync.Mutex
+	brokers map[string]messageBroker
+}
+
+func (m *messageBrokers) broker(ctx context.Context, name string, vm *otto.Otto) (messageBroker, error) {
+	config, ok := m.configs[name]
+	if !ok {
+		return nil, fmt.Errorf("broker '%s' is not defined in 'brokers' of the load test", name)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if broker, ok := m.brokers[name]; ok {
+		return broker, nil
+	}
+
+	broker, err := messageBrokerFactories[config.Type](ctx, config, vm)
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to broker '%s': %s", name, err)
+	}
+
+	m.brokers[name] = broker
+
+	return broker, nil
+}
+
+// Close closes all opened broker connections
+func (m *messageBrokers) Close() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for name, broker := range m.brokers {
+		broker.Close()
+		delete(m.brokers, name)
+	}
+}
+
+func newMessageBrokers(configs map[string]*MessageBrokerConfig) *messageBrokers {
+	return &messageBrokers{
+		configs: configs,
+		tracker: newMessageTracker(),
+		brokers: map[string]messageBroker{},
+	}
+}
+
+func contextWithMessageBrokers(ctx context.Context, brokers *messageBrokers) context.Context {
+	return context.WithValue(ctx, contextKeyMessageBrokers, brokers)
+}
+
+func messageBrokersFromContext(ctx context.Context) *messageBrokers {
+	brokers, ok := ctx.Value(contextKeyMessageBrokers).(*messageBrokers)
+	if !ok {
+		return nil
+	}
+
+	return brokers
+}
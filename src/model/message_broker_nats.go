@@ -0,0 +1,161 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/robertkrimen/otto"
+)
+
+// natsFlushTimeout is used when the context has no deadline
+const natsFlushTimeout = 10 * time.Second
+
+type natsMessageBroker struct {
+	conn *nats.Conn
+}
+
+type natsMessageSubscription struct {
+	subscription *nats.Subscription
+}
+
+func (n *natsMessageSubscription) Next(ctx context.Context) (*brokerMessage, error) {
+	msg, err := n.subscription.NextMsgWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &brokerMessage{
+		Headers: map[string]string{},
+		Payload: msg.Data,
+	}
+
+	for key := range msg.Header {
+		message.Headers[key] = msg.Header.Get(key)
+	}
+
+	return message, nil
+}
+
+// flush waits until the server has processed all pending messages
+func (n *natsMessageBroker) flush(ctx context.Context) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel func()
+
+		ctx, cancel = context.WithTimeout(ctx, natsFlushTimeout)
+		defer cancel()
+	}
+
+	return n.conn.FlushWithContext(ctx)
+}
+
+func (n *natsMessageSubscription) Close() error {
+	return n.subscription.Unsubscribe()
+}
+
+// Publish buffers the message, it is sent at the latest on Flush
+func (n *natsMessageBroker) Publish(ctx context.Context, destination string, message *brokerMessage) error {
+	msg := nats.NewMsg(destination)
+	msg.Data = message.Payload
+
+	for key, value := range message.Headers {
+		msg.Header.Set(key, value)
+	}
+
+	return n.conn.PublishMsg(msg)
+}
+
+// Flush waits until the server has processed all published messages
+func (n *natsMessageBroker) Flush(ctx context.Context) error {
+	return n.flush(ctx)
+}
+
+// Subscribe subscribes to the subject, with a group the messages are distributed within the queue group
+func (n *natsMessageBroker) Subscribe(ctx context.Context, destination string, group string) (messageSubscription, error) {
+	var subscription *nats.Subscription
+	var err error
+
+	if group != "" {
+		subscription, err = n.conn.QueueSubscribeSync(destination, group)
+	} else {
+		subscription, err = n.conn.SubscribeSync(destination)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure the subscription is active before messages get published
+	err = n.flush(ctx)
+	if err != nil {
+		subscription.Unsubscribe()
+
+		return nil, err
+	}
+
+	return &natsMessageSubscription{
+		subscription: subscription,
+	}, nil
+}
+
+func (n *natsMessageBroker) Close() error {
+	n.conn.Close()
+
+	return nil
+}
+
+func newNatsMessageBroker(ctx context.Context, config *MessageBrokerConfig, vm *otto.Otto) (messageBroker, error) {
+	url, err := interpolateString(vm, config.URL)
+	if err != nil {
+		return nil, fmt.Errorf("error in 'url': %s", err)
+	}
+
+	options := []nats.Option{
+		nats.Name("loadtest"),
+	}
+
+	if config.Username.Valid || config.Password.Valid {
+		username, err := interpolateString(vm, config.Username.String)
+		if err != nil {
+			return nil, fmt.Errorf("error in 'username': %s", err)
+		}
+
+		password, err := interpolateString(vm, config.Password.String)
+		if err != nil {
+			return nil, fmt.Errorf("error in 'password': %s", err)
+		}
+
+		options = append(options, nats.UserInfo(username, password))
+	}
+
+	if config.Token.Valid {
+		token, err := interpolateString(vm, config.Token.String)
+		if err != nil {
+			return nil, fmt.Errorf("error in 'token': %s", err)
+		}
+
+		options = append(options, nats.Token(token))
+	}
+
+	if config.TLS != nil {
+		tlsConfig, err := config.TLS.NewTLSConfig(ctx, vm)
+		if err != nil {
+			return nil, err
+		}
+
+		options = append(options, nats.Secure(tlsConfig))
+	}
+
+	conn, err := nats.Connect(url, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &natsMessageBroker{
+		conn: conn,
+	}, nil
+}
+
+var _ messageBroker = (*natsMessageBroker)(nil)
+var _ messageSubscription = (*natsMessageSubscription)(nil)
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMessageTracker(t *testing.T) {
+	tracker := newMessageTracker()
+
+	beforeSubscribe := &brokerMessage{Headers: map[string]string{}}
+	tracker.stamp(beforeSubscribe)
+
+	if len(tracker.pending) != 0 {
+		t.Fatalf("messages must not be tracked before a consumer subscribed")
+	}
+
+	if tracker.lost().Valid {
+		t.Errorf("lost must not be valid before a consumer subscribed")
+	}
+
+	tracker.subscribe()
+
+	consumed := &brokerMessage{Headers: map[string]string{}}
+	tracker.stamp(consumed)
+
+	expired := &brokerMessage{Headers: map[string]string{}}
+	tracker.stamp(expired)
+
+	latency, duplicate := tracker.consume(beforeSubscribe)
+	if latency != nil || duplicate {
+		t.Errorf("message published before subscribing: latency = %v, duplicate = %v", latency, duplicate)
+	}
+
+	latency, duplicate = tracker.consume(consumed)
+	if latency == nil || duplicate {
+		t.Errorf("tracked message: latency = %v, duplicate = %v", latency, duplicate)
+	}
+
+	_, duplicate = tracker.consume(consumed)
+	if !duplicate {
+		t.Errorf("second copy of a message must be a duplicate")
+	}
+
+	tracker.mutex.Lock()
+	tracker.expire(time.Now().Add(messageTrackerTTL))
+	tracker.mutex.Unlock()
+
+	if len(tracker.pending) != 0 {
+		t.Errorf("expired message must not be pending")
+	}
+
+	if lost := tracker.lost(); lost.Int64 != 1 {
+		t.Errorf("lost = %d, expected 1", lost.Int64)
+	}
+
+	latency, duplicate = tracker.consume(expired)
+	if latency != nil || duplicate {
+		t.Errorf("expired message: latency = %v, duplicate = %v", latency, duplicate)
+	}
+}
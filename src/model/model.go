@@ -1,6 +1,7 @@
 package model
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"sync"
@@ -8,34 +9,63 @@ import (
 
 	"github.com/indece-official/loadtest/src/report"
 	"github.com/indece-official/loadtest/src/stats"
+	"github.com/indece-official/loadtest/src/utils"
 	"github.com/robertkrimen/otto"
 	log "github.com/sirupsen/logrus"
 	"gopkg.in/guregu/null.v4"
 )
 
 type StepExecutionStats struct {
-	DurationRequest  *time.Duration
-	DurationResponse *time.Duration
-	Code             null.String
-	BytesSent        null.Int
-	BytesReceived    null.Int
+	DurationRequest        *time.Duration
+	DurationResponse       *time.Duration
+	DurationPause          *time.Duration
+	DurationDNS            *time.Duration
+	DurationConnect        *time.Duration
+	DurationTLS            *time.Duration
+	DurationTTFB           *time.Duration
+	DurationTransfer       *time.Duration
+	DurationRoundTrip      *time.Duration
+	ConnectionReused       null.Bool
+	Code                   null.String
+	Protocol               null.String
+	BytesSent              null.Int
+	BytesReceived          null.Int
+	BytesReceivedBody      null.Int
+	MessagesSent           null.Int
+	MessagesReceived       null.Int
+	MessagesDuplicate      null.Int
+	MessagesDropped        null.Int
+	Rows                   null.Int
+	Branch                 null.String
+	BranchWeight           null.Float
+	Operations             []*stats.StepOperation
+	DurationsEndToEnd      []time.Duration
+	DurationFirstEvent     *time.Duration
+	DurationsBetweenEvents []time.Duration
+
+	// DurationPauseNested is the pause of child steps, which isn't part
+	// of the latency of this step but is recorded by the children
+	DurationPauseNested time.Duration
 }
 
 type IRunnable interface {
 	Validate() error
-	Execute(path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) error
+	Execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) error
 }
 
 type IRunnableStep interface {
 	Validate() error
-	Execute(path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error)
+	Execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error)
 }
 
 type LoadTest struct {
-	Name     string                 `yaml:"name"`
-	Disabled null.Bool              `yaml:"disabled"`
-	Vars     map[string]interface{} `yaml:"vars"`
-	Steps    []*LoadTestStep        `yaml:"steps"`
+	Name       string                          `yaml:"name"`
+	Disabled   null.Bool                       `yaml:"disabled"`
+	Vars       map[string]interface{}          `yaml:"vars"`
+	HttpClient *HttpClientConfig               `yaml:"http_client"`
+	Databases  map[string]*SqlDatabaseConfig   `yaml:"databases"`
+	Brokers    map[string]*MessageBrokerConfig `yaml:"brokers"`
+	Steps      []*LoadTestStep                 `yaml:"steps"`
 }
 
 func (l *LoadTest) Validate() error {
@@ -47,6 +77,27 @@ func (l *LoadTest) Validate() error {
 		return fmt.Errorf("no step defined for load test '%s'", l.Name)
 	}
 
+	if l.HttpClient != nil {
+		err := l.HttpClient.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid http_client of load test '%s': %s", l.Name, err)
+		}
+	}
+
+	for name, database := range l.Databases {
+		err := database.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid database '%s' of load test '%s': %s", name, l.Name, err)
+		}
+	}
+
+	for name, broker := range l.Brokers {
+		err := broker.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid broker '%s' of load test '%s': %s", name, l.Name, err)
+		}
+	}
+
 	for i, step := range l.Steps {
 		err := step.Validate()
 		if err != nil {
@@ -57,7 +108,7 @@ func (l *LoadTest) Validate() error {
 	return nil
 }
 
-func (l *LoadTest) Execute(path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) error {
+func (l *LoadTest) Execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) error {
 	newPath := append(path, l.Name)
 
 	log.Debugf("Starting test %s", strings.Join(newPath, "."))
@@ -69,7 +120,34 @@ func (l *LoadTest) Execute(path []string, vm *otto.Otto, runStats *stats.RunStat
 		}
 	}
 
+	virtualUser, err := NewVirtualUser(ctx, l.HttpClient, vm)
+	if err != nil {
+		return fmt.Errorf("can't create virtual user: %s", err)
+	}
+	defer virtualUser.Close()
+
+	databases := newSqlDatabases(l.Databases)
+	defer databases.Close()
+
+	brokers := newMessageBrokers(l.Brokers)
+	defer brokers.Close()
+
+	defer func() {
+		// Messages still pending after an interruption aren't lost
+		if lost := brokers.tracker.lost(); lost.Valid && ctx.Err() == nil {
+			runStats.AddMessagesLost(lost.Int64)
+		}
+	}()
+
+	ctx = contextWithVirtualUser(ctx, virtualUser)
+	ctx = contextWithSqlDatabases(ctx, databases)
+	ctx = contextWithMessageBrokers(ctx, brokers)
+
 	for i, step := range l.Steps {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
 		var subPath []string
 
 		if step.Name.Valid {
@@ -78,7 +156,20 @@ func (l *LoadTest) Execute(path []string, vm *otto.Otto, runStats *stats.RunStat
 			subPath = append(newPath, fmt.Sprintf("%d", i))
 		}
 
-		err := step.Execute(subPath, vm, runStats, report)
+		err := step.Execute(ctx, subPath, vm, runStats, report)
+		if err != nil && isAbortError(err, ErrorPolicyAbortIteration) {
+			// Outside of a loop there is no iteration to abort
+			log.Warnf("Step %d of load test %s failed outside of a loop, continuing: %s", i, l.Name, err)
+
+			continue
+		}
+
+		if err != nil && isAbortError(err, ErrorPolicyAbortThread, ErrorPolicyAbortTest) {
+			log.Warnf("Aborted load test %s: %s", l.Name, err)
+
+			break
+		}
+
 		if err != nil {
 			return fmt.Errorf("step %d of load test %s failed: %s", i, l.Name, err)
 		}
@@ -92,16 +183,50 @@ func (l *LoadTest) Execute(path []string, vm *otto.Otto, runStats *stats.RunStat
 var _ IRunnable = (*LoadTest)(nil)
 
 type LoadTestStep struct {
-	Name     null.String          `yaml:"name"`
-	Disabled null.Bool            `yaml:"disabled"`
-	Loop     *LoadTestStepLoop    `yaml:"loop"`
-	Log      *LoadTestStepLog     `yaml:"log"`
-	Threads  *LoadTestStepThreads `yaml:"threads"`
-	Http     *LoadTestStepHttp    `yaml:"http"`
-	Exec     *LoadTestStepExec    `yaml:"exec"`
+	Name      null.String            `yaml:"name"`
+	Disabled  null.Bool              `yaml:"disabled"`
+	Loop      *LoadTestStepLoop      `yaml:"loop"`
+	Log       *LoadTestStepLog       `yaml:"log"`
+	Threads   *LoadTestStepThreads   `yaml:"threads"`
+	Http      *LoadTestStepHttp      `yaml:"http"`
+	Exec      *LoadTestStepExec      `yaml:"exec"`
+	Sleep     *LoadTestStepSleep     `yaml:"sleep"`
+	If        *LoadTestStepIf        `yaml:"if"`
+	Switch    *LoadTestStepSwitch    `yaml:"switch"`
+	Weighted  *LoadTestStepWeighted  `yaml:"weighted"`
+	WebSocket *LoadTestStepWebSocket `yaml:"websocket"`
+	Grpc      *LoadTestStepGrpc      `yaml:"grpc"`
+	TCP       *LoadTestStepTCP       `yaml:"tcp"`
+	UDP       *LoadTestStepUDP       `yaml:"udp"`
+	DNS       *LoadTestStepDNS       `yaml:"dns"`
+	Sql       *LoadTestStepSql       `yaml:"sql"`
+	Redis     *LoadTestStepRedis     `yaml:"redis"`
+	Publish   *LoadTestStepPublish   `yaml:"publish"`
+	Consume   *LoadTestStepConsume   `yaml:"consume"`
+	SSE       *LoadTestStepSSE       `yaml:"sse"`
+	GraphQL   *LoadTestStepGraphQL   `yaml:"graphql"`
+	Command   *LoadTestStepCommand   `yaml:"command"`
+	OnError   ErrorPolicy            `yaml:"on_error"`
+	Retry     *StepRetry             `yaml:"retry"`
 }
 
 func (l *LoadTestStep) Validate() error {
+	err := l.OnError.Validate()
+	if err != nil {
+		return fmt.Errorf("error in step '%s': invalid on_error: %s", l.Name.String, err)
+	}
+
+	if l.OnError == ErrorPolicyRetry && l.Retry == nil {
+		return fmt.Errorf("error in step '%s': on_error 'retry' requires 'retry'", l.Name.String)
+	}
+
+	if l.Retry != nil {
+		err = l.Retry.Validate()
+		if err != nil {
+			return fmt.Errorf("error in step '%s': invalid retry: %s", l.Name.String, err)
+		}
+	}
+
 	switch {
 	case l.Loop != nil:
 		err := l.Loop.Validate()
@@ -128,14 +253,172 @@ func (l *LoadTestStep) Validate() error {
 		if err != nil {
 			return fmt.Errorf("error in step '%s': invalid exec: %s", l.Name.String, err)
 		}
+	case l.Sleep != nil:
+		err := l.Sleep.Validate()
+		if err != nil {
+			return fmt.Errorf("error in step '%s': invalid sleep: %s", l.Name.String, err)
+		}
+	case l.If != nil:
+		err := l.If.Validate()
+		if err != nil {
+			return fmt.Errorf("error in step '%s': invalid if: %s", l.Name.String, err)
+		}
+	case l.Switch != nil:
+		err := l.Switch.Validate()
+		if err != nil {
+			return fmt.Errorf("error in step '%s': invalid switch: %s", l.Name.String, err)
+		}
+	case l.Weighted != nil:
+		err := l.Weighted.Validate()
+		if err != nil {
+			return fmt.Errorf("error in step '%s': invalid weighted: %s", l.Name.String, err)
+		}
+	case l.WebSocket != nil:
+		err := l.WebSocket.Validate()
+		if err != nil {
+			return fmt.Errorf("error in step '%s': invalid websocket: %s", l.Name.String, err)
+		}
+	case l.Grpc != nil:
+		err := l.Grpc.Validate()
+		if err != nil {
+			return fmt.Errorf("error in step '%s': invalid grpc: %s", l.Name.String, err)
+		}
+	case l.TCP != nil:
+		err := l.TCP.Validate()
+		if err != nil {
+			return fmt.Errorf("error in step '%s': invalid tcp: %s", l.Name.String, err)
+		}
+	case l.UDP != nil:
+		err := l.UDP.Validate()
+		if err != nil {
+			return fmt.Errorf("error in step '%s': invalid udp: %s", l.Name.String, err)
+		}
+	case l.DNS != nil:
+		err := l.DNS.Validate()
+		if err != nil {
+			return fmt.Errorf("error in step '%s': invalid dns: %s", l.Name.String, err)
+		}
+	case l.Sql != nil:
+		err := l.Sql.Validate()
+		if err != nil {
+			return fmt.Errorf("error in step '%s': invalid sql: %s", l.Name.String, err)
+		}
+	case l.Redis != nil:
+		err := l.Redis.Validate()
+		if err != nil {
+			return fmt.Errorf("error in step '%s': invalid redis: %s", l.Name.String, err)
+		}
+	case l.Publish != nil:
+		err := l.Publish.Validate()
+		if err != nil {
+			return fmt.Errorf("error in step '%s': invalid publish: %s", l.Name.String, err)
+		}
+	case l.Consume != nil:
+		err := l.Consume.Validate()
+		if err != nil {
+			return fmt.Errorf("error in step '%s': invalid consume: %s", l.Name.String, err)
+		}
+	case l.SSE != nil:
+		err := l.SSE.Validate()
+		if err != nil {
+			return fmt.Errorf("error in step '%s': invalid sse: %s", l.Name.String, err)
+		}
+	case l.GraphQL != nil:
+		err := l.GraphQL.Validate()
+		if err != nil {
+			return fmt.Errorf("error in step '%s': invalid graphql: %s", l.Name.String, err)
+		}
+	case l.Command != nil:
+		err := l.Command.Validate()
+		if err != nil {
+			return fmt.Errorf("error in step '%s': invalid command: %s", l.Name.String, err)
+		}
 	default:
-		return fmt.Errorf("loop must contain one child of 'loop' | 'threads' | 'log' | 'http'")
+		return fmt.Errorf("loop must contain one child of 'loop' | 'threads' | 'log' | 'http' | 'exec' | 'sleep' | 'if' | 'switch' | 'weighted' | 'websocket' | 'grpc' | 'tcp' | 'udp' | 'dns' | 'sql' | 'redis' | 'publish' | 'consume' | 'sse' | 'graphql' | 'command'")
 	}
 
 	return nil
 }
 
-func (l *LoadTestStep) Execute(path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) error {
+func (l *LoadTestStep) executeChild(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, bool, error) {
+	switch {
+	case l.Loop != nil:
+		stepStats, err := l.Loop.Execute(ctx, path, vm, runStats, report)
+		return stepStats, true, err
+	case l.Threads != nil:
+		stepStats, err := l.Threads.Execute(ctx, path, vm, runStats, report)
+		return stepStats, true, err
+	case l.Log != nil:
+		stepStats, err := l.Log.Execute(ctx, path, vm, runStats, report)
+		return stepStats, false, err
+	case l.Http != nil:
+		stepStats, err := l.Http.Execute(ctx, path, vm, runStats, report)
+		return stepStats, false, err
+	case l.Exec != nil:
+		stepStats, err := l.Exec.Execute(ctx, path, vm, runStats, report)
+		return stepStats, false, err
+	case l.Sleep != nil:
+		stepStats, err := l.Sleep.Execute(ctx, path, vm, runStats, report)
+		return stepStats, false, err
+	case l.If != nil:
+		stepStats, err := l.If.Execute(ctx, path, vm, runStats, report)
+		return stepStats, true, err
+	case l.Switch != nil:
+		stepStats, err := l.Switch.Execute(ctx, path, vm, runStats, report)
+		return stepStats, true, err
+	case l.Weighted != nil:
+		stepStats, err := l.Weighted.Execute(ctx, path, vm, runStats, report)
+		return stepStats, true, err
+	case l.WebSocket != nil:
+		stepStats, err := l.WebSocket.Execute(ctx, path, vm, runStats, report)
+		return stepStats, false, err
+	case l.Grpc != nil:
+		stepStats, err := l.Grpc.Execute(ctx, path, vm, runStats, report)
+		return stepStats, false, err
+	case l.TCP != nil:
+		stepStats, err := l.TCP.Execute(ctx, path, vm, runStats, report)
+		return stepStats, false, err
+	case l.UDP != nil:
+		stepStats, err := l.UDP.Execute(ctx, path, vm, runStats, report)
+		return stepStats, false, err
+	case l.DNS != nil:
+		stepStats, err := l.DNS.Execute(ctx, path, vm, runStats, report)
+		return stepStats, false, err
+	case l.Sql != nil:
+		stepStats, err := l.Sql.Execute(ctx, path, vm, runStats, report)
+		return stepStats, false, err
+	case l.Redis != nil:
+		stepStats, err := l.Redis.Execute(ctx, path, vm, runStats, report)
+		return stepStats, false, err
+	case l.Publish != nil:
+		stepStats, err := l.Publish.Execute(ctx, path, vm, runStats, report)
+		return stepStats, false, err
+	case l.Consume != nil:
+		stepStats, err := l.Consume.Execute(ctx, path, vm, runStats, report)
+		return stepStats, false, err
+	case l.SSE != nil:
+		stepStats, err := l.SSE.Execute(ctx, path, vm, runStats, report)
+		return stepStats, false, err
+	case l.GraphQL != nil:
+		stepStats, err := l.GraphQL.Execute(ctx, path, vm, runStats, report)
+		return stepStats, false, err
+	case l.Command != nil:
+		stepStats, err := l.Command.Execute(ctx, path, vm, runStats, report)
+		return stepStats, false, err
+	}
+
+	return nil, false, nil
+}
+
+func (l *LoadTestStep) Execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) error {
+	_, err := l.execute(ctx, path, vm, runStats, report)
+
+	return err
+}
+
+// execute runs the step and returns the time it paused (think time, pacing and retry
+// backoff of the step and its children), which parents don't count as their latency
+func (l *LoadTestStep) execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (time.Duration, error) {
 	name := l.Name.String
 	if name == "" {
 		name = strings.Join(path, ".")
@@ -154,69 +437,173 @@ func (l *LoadTestStep) Execute(path []string, vm *otto.Otto, runStats *stats.Run
 			DurationTotal:   0,
 		})
 
-		return nil
+		return 0, nil
 	}
 
-	start := time.Now()
+	durationPaused := time.Duration(0)
 
-	var err error
-	var stepStats *StepExecutionStats
-	isGroup := false
+	for attempt := int64(0); ; attempt++ {
+		start := time.Now()
 
-	switch {
-	case l.Loop != nil:
-		isGroup = true
-		stepStats, err = l.Loop.Execute(path, vm, runStats, report)
-	case l.Threads != nil:
-		isGroup = true
-		stepStats, err = l.Threads.Execute(path, vm, runStats, report)
-	case l.Log != nil:
-		stepStats, err = l.Log.Execute(path, vm, runStats, report)
-	case l.Http != nil:
-		stepStats, err = l.Http.Execute(path, vm, runStats, report)
-	case l.Exec != nil:
-		stepStats, err = l.Exec.Execute(path, vm, runStats, report)
-	}
+		stepStats, isGroup, err := l.executeChild(ctx, path, vm, runStats, report)
 
-	duration := time.Since(start)
+		duration := time.Since(start)
 
-	execution := &stats.StepExecution{
-		IsGroup:         isGroup,
-		Status:          stats.StepExecutionStatusSuccess,
-		HasExplicitName: l.Name.Valid,
-		StartTime:       start,
-		Name:            name,
-		DurationTotal:   duration,
-	}
+		if ctx.Err() != nil {
+			// Interrupted executions are not part of the stats
+			log.Debugf("Interrupted test step %s", name)
 
-	if stepStats != nil {
-		execution.BytesReceived = stepStats.BytesReceived
-		execution.BytesSent = stepStats.BytesSent
-		execution.DurationRequest = stepStats.DurationRequest
-		execution.DurationResponse = stepStats.DurationResponse
-		execution.Code = stepStats.Code
-	}
+			return durationPaused, ctx.Err()
+		}
 
-	if err != nil {
-		execution.Error = err
-		execution.Status = stats.StepExecutionStatusFailed
+		// Pauses (think time & pacing) are not part of the step latency
+		if stepStats != nil && stepStats.DurationPause != nil {
+			duration -= *stepStats.DurationPause
+			durationPaused += *stepStats.DurationPause
+		}
 
-		log.Errorf("Test step %s failed: %s", name, err)
-	}
+		if stepStats != nil {
+			duration -= stepStats.DurationPauseNested
+			durationPaused += stepStats.DurationPauseNested
+		}
 
-	runStats.AddStepExecution(execution)
+		execution := &stats.StepExecution{
+			IsGroup:         isGroup,
+			Status:          stats.StepExecutionStatusSuccess,
+			HasExplicitName: l.Name.Valid,
+			StartTime:       start,
+			Name:            name,
+			DurationTotal:   duration,
+		}
 
-	log.Debugf("Finished test step %s", name)
+		if stepStats != nil {
+			execution.BytesReceived = stepStats.BytesReceived
+			execution.BytesSent = stepStats.BytesSent
+			execution.BytesReceivedBody = stepStats.BytesReceivedBody
+			execution.DurationRequest = stepStats.DurationRequest
+			execution.DurationResponse = stepStats.DurationResponse
+			execution.DurationPause = stepStats.DurationPause
+			execution.DurationDNS = stepStats.DurationDNS
+			execution.DurationConnect = stepStats.DurationConnect
+			execution.DurationTLS = stepStats.DurationTLS
+			execution.DurationTTFB = stepStats.DurationTTFB
+			execution.DurationTransfer = stepStats.DurationTransfer
+			execution.DurationRoundTrip = stepStats.DurationRoundTrip
+			execution.MessagesSent = stepStats.MessagesSent
+			execution.MessagesReceived = stepStats.MessagesReceived
+			execution.MessagesDuplicate = stepStats.MessagesDuplicate
+			execution.MessagesDropped = stepStats.MessagesDropped
+			execution.Rows = stepStats.Rows
+			execution.ConnectionReused = stepStats.ConnectionReused
+			execution.Code = stepStats.Code
+			execution.Protocol = stepStats.Protocol
+			execution.Branch = stepStats.Branch
+			execution.BranchWeight = stepStats.BranchWeight
+			execution.Operations = stepStats.Operations
+			execution.DurationsEndToEnd = stepStats.DurationsEndToEnd
+			execution.DurationFirstEvent = stepStats.DurationFirstEvent
+			execution.DurationsBetweenEvents = stepStats.DurationsBetweenEvents
+		}
 
-	return nil
+		if err != nil && isAbortError(err, ErrorPolicyAbortIteration, ErrorPolicyAbortThread, ErrorPolicyAbortTest) {
+			// Abort requested by a child step, pass it on to the parents
+			execution.Error = err
+			execution.Status = stats.StepExecutionStatusFailed
+
+			runStats.AddStepExecution(execution)
+
+			return durationPaused, err
+		}
+
+		if err != nil && l.Retry != nil && attempt < l.Retry.Count {
+			execution.Error = err
+			execution.Status = stats.StepExecutionStatusRetried
+
+			runStats.AddStepExecution(execution)
+
+			backoff, errBackoff := l.Retry.BackoffDuration(attempt)
+			if errBackoff != nil {
+				return durationPaused, errBackoff
+			}
+
+			log.Warnf("Test step %s failed, retrying in %s (%d/%d): %s", name, backoff, attempt+1, l.Retry.Count, err)
+
+			startBackoff := time.Now()
+
+			err = utils.SleepContext(ctx, backoff)
+
+			durationPaused += time.Since(startBackoff)
+
+			if err != nil {
+				return durationPaused, err
+			}
+
+			continue
+		}
+
+		if err != nil {
+			execution.Error = err
+			execution.Status = stats.StepExecutionStatusFailed
+
+			log.Errorf("Test step %s failed: %s", name, err)
+		}
+
+		runStats.AddStepExecution(execution)
+
+		log.Debugf("Finished test step %s", name)
+
+		if err != nil {
+			switch l.OnError {
+			case ErrorPolicyAbortIteration,
+				ErrorPolicyAbortThread,
+				ErrorPolicyAbortTest:
+				return durationPaused, &StepAbortError{
+					Policy: l.OnError,
+					Err:    fmt.Errorf("test step %s failed: %s", name, err),
+				}
+			}
+		}
+
+		return durationPaused, nil
+	}
 }
 
 var _ IRunnable = (*LoadTestStep)(nil)
 
+// executeSteps executes a list of child steps sequentially and returns the time they paused
+func executeSteps(ctx context.Context, steps []*LoadTestStep, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (time.Duration, error) {
+	durationPaused := time.Duration(0)
+
+	for i, step := range steps {
+		if ctx.Err() != nil {
+			return durationPaused, ctx.Err()
+		}
+
+		var subPath []string
+
+		if step.Name.Valid {
+			subPath = append(path, step.Name.String)
+		} else {
+			subPath = append(path, fmt.Sprintf("%d", i))
+		}
+
+		durationPausedStep, err := step.execute(ctx, subPath, vm, runStats, report)
+
+		durationPaused += durationPausedStep
+
+		if err != nil {
+			return durationPaused, fmt.Errorf("step %d failed: %w", i, err)
+		}
+	}
+
+	return durationPaused, nil
+}
+
 type LoadTestStepLoop struct {
 	Count           null.Int               `yaml:"count"`
 	CounterVariable null.String            `yaml:"counter_variable"`
 	While           ExecutableStringOrNull `yaml:"while"`
+	Pacing          null.String            `yaml:"pacing"`
 	Steps           []*LoadTestStep        `yaml:"steps"`
 }
 
@@ -229,6 +616,13 @@ func (l *LoadTestStepLoop) Validate() error {
 		return fmt.Errorf("count must be greater 0")
 	}
 
+	if l.Pacing.Valid {
+		_, err := parseDurationOrNull("pacing", l.Pacing)
+		if err != nil {
+			return err
+		}
+	}
+
 	for i, step := range l.Steps {
 		err := step.Validate()
 		if err != nil {
@@ -239,33 +633,58 @@ func (l *LoadTestStepLoop) Validate() error {
 	return nil
 }
 
-func (l *LoadTestStepLoop) Execute(path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error) {
+func (l *LoadTestStepLoop) Execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error) {
 	counter := int64(0)
 
+	pacing := time.Duration(0)
+	if l.Pacing.Valid {
+		var err error
+
+		pacing, err = parseDurationOrNull("pacing", l.Pacing)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	stepStats := &StepExecutionStats{}
+	durationPause := time.Duration(0)
+	stepStats.DurationPause = &durationPause
+
 	for {
+		if ctx.Err() != nil {
+			return stepStats, ctx.Err()
+		}
+
 		if l.Count.Valid && counter >= l.Count.Int64 {
 			// Finished
-			return nil, nil
+			return stepStats, nil
 		}
 
 		if l.While.Valid {
-			val, err := l.While.Execute(vm)
+			val, err := l.While.Execute(ctx, vm)
 			if err != nil {
-				return nil, fmt.Errorf("error executing 'while' condition: %s", err)
+				return stepStats, fmt.Errorf("error executing 'while' condition: %s", err)
 			}
 
 			boolVal, err := val.ToBoolean()
 			if err != nil {
-				return nil, fmt.Errorf("'while' condition must return a boolean")
+				return stepStats, fmt.Errorf("'while' condition must return a boolean")
 			}
 
 			if !boolVal {
 				// Finished
 
-				return nil, nil
+				return stepStats, nil
 			}
 		}
 
+		startIteration := time.Now()
+
+		virtualUser := virtualUserFromContext(ctx)
+		if virtualUser != nil {
+			virtualUser.StartIteration()
+		}
+
 		counterVariable := l.CounterVariable.String
 		if counterVariable == "" {
 			counterVariable = "counter"
@@ -282,9 +701,30 @@ func (l *LoadTestStepLoop) Execute(path []string, vm *otto.Otto, runStats *stats
 				subPath = append(path, fmt.Sprintf("%d", i))
 			}
 
-			err := step.Execute(subPath, vm, runStats, report)
+			durationPausedStep, err := step.execute(ctx, subPath, vm, runStats, report)
+
+			stepStats.DurationPauseNested += durationPausedStep
+
+			if err != nil && isAbortError(err, ErrorPolicyAbortIteration) {
+				log.Debugf("Aborted iteration %d of loop: %s", counter, err)
+
+				break
+			}
+
+			if err != nil {
+				return stepStats, fmt.Errorf("step %d of loop failed: %w", i, err)
+			}
+		}
+
+		// Enforce the minimum duration of an iteration
+		durationIteration := time.Since(startIteration)
+		if pacing > durationIteration {
+			err := utils.SleepContext(ctx, pacing-durationIteration)
+
+			durationPause += time.Since(startIteration) - durationIteration
+
 			if err != nil {
-				return nil, fmt.Errorf("step %d of loop failed: %s", i, err)
+				return stepStats, err
 			}
 		}
 
@@ -295,9 +735,10 @@ func (l *LoadTestStepLoop) Execute(path []string, vm *otto.Otto, runStats *stats
 var _ IRunnableStep = (*LoadTestStepLoop)(nil)
 
 type LoadTestStepThreads struct {
-	Count           int64           `yaml:"count"`
-	CounterVariable null.String     `yaml:"counter_variable"`
-	Steps           []*LoadTestStep `yaml:"steps"`
+	Count           int64             `yaml:"count"`
+	CounterVariable null.String       `yaml:"counter_variable"`
+	HttpClient      *HttpClientConfig `yaml:"http_client"`
+	Steps           []*LoadTestStep   `yaml:"steps"`
 }
 
 func (l *LoadTestStepThreads) Validate() error {
@@ -305,6 +746,13 @@ func (l *LoadTestStepThreads) Validate() error {
 		return fmt.Errorf("count must be greater 0")
 	}
 
+	if l.HttpClient != nil {
+		err := l.HttpClient.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid http_client: %s", err)
+		}
+	}
+
 	for i, step := range l.Steps {
 		err := step.Validate()
 		if err != nil {
@@ -315,9 +763,24 @@ func (l *LoadTestStepThreads) Validate() error {
 	return nil
 }
 
-func (l *LoadTestStepThreads) Execute(path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error) {
+func (l *LoadTestStepThreads) Execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error) {
 	waitGroup := sync.WaitGroup{}
 	mutexVm := sync.Mutex{}
+	mutexAbort := sync.Mutex{}
+
+	// abortErr is set if one thread requests to abort the test,
+	// the other threads are then stopped via the context
+	var abortErr error
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Each thread is a separate virtual user with its own connections,
+	// inheriting the http client config of the parent if not overwritten
+	httpClientConfig := l.HttpClient
+	if httpClientConfig == nil && virtualUserFromContext(ctx) != nil {
+		httpClientConfig = virtualUserFromContext(ctx).HttpClientConfig()
+	}
 
 	for i := 0; i < int(l.Count); i++ {
 		waitGroup.Add(1)
@@ -335,6 +798,16 @@ func (l *LoadTestStepThreads) Execute(path []string, vm *otto.Otto, runStats *st
 
 			threadVm.Set(counterVariable, counter)
 
+			virtualUser, err := NewVirtualUser(ctx, httpClientConfig, threadVm)
+			if err != nil {
+				log.Errorf("Can't create virtual user for thread %d: %s", counter, err)
+
+				return
+			}
+			defer virtualUser.Close()
+
+			ctx := contextWithVirtualUser(ctx, virtualUser)
+
 			for i, step := range l.Steps {
 				var subPath []string
 
@@ -344,9 +817,36 @@ func (l *LoadTestStepThreads) Execute(path []string, vm *otto.Otto, runStats *st
 					subPath = append(path, fmt.Sprintf("%d", i))
 				}
 
-				err := step.Execute(subPath, vm, runStats, report)
+				if ctx.Err() != nil {
+					return
+				}
+
+				err := step.Execute(ctx, subPath, threadVm, runStats, report)
+				if err != nil && isAbortError(err, ErrorPolicyAbortTest) {
+					mutexAbort.Lock()
+					if abortErr == nil {
+						abortErr = fmt.Errorf("step %d of thread %d failed: %w", i, counter, err)
+					}
+					mutexAbort.Unlock()
+
+					cancel()
+
+					return
+				}
+
+				if err != nil && ctx.Err() != nil {
+					return
+				}
+
+				if err != nil && isAbortError(err, ErrorPolicyAbortIteration) {
+					// Outside of a loop there is no iteration to abort
+					log.Warnf("Step %d of thread %d failed outside of a loop, continuing: %s", i, counter, err)
+
+					continue
+				}
+
 				if err != nil {
-					log.Errorf("Step %d of thread failed: %s", i, err)
+					log.Warnf("Aborted thread %d: %s", counter, err)
 
 					return
 				}
@@ -356,7 +856,11 @@ func (l *LoadTestStepThreads) Execute(path []string, vm *otto.Otto, runStats *st
 
 	waitGroup.Wait()
 
-	return nil, nil
+	if abortErr != nil {
+		return nil, abortErr
+	}
+
+	return nil, ctx.Err()
 }
 
 var _ IRunnableStep = (*LoadTestStepThreads)(nil)
@@ -378,7 +882,7 @@ func (l *LoadTestStepLog) Validate() error {
 	return nil
 }
 
-func (l *LoadTestStepLog) Execute(path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error) {
+func (l *LoadTestStepLog) Execute(ctx context.Context, path []string, vm *otto.Otto, runStats *stats.RunStats, report *report.Report) (*StepExecutionStats, error) {
 	if l.Message.Valid {
 		log.Infof("[%s]: %s", strings.Join(path, "."), l.Message.String)
 
@@ -386,7 +890,7 @@ func (l *LoadTestStepLog) Execute(path []string, vm *otto.Otto, runStats *stats.
 	}
 
 	if l.Expression.Valid {
-		val, err := l.Expression.Execute(vm)
+		val, err := l.Expression.Execute(ctx, vm)
 		if err != nil {
 			return nil, fmt.Errorf("can't execute expr: %s", err)
 		}
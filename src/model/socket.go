@@ -0,0 +1,447 @@
+package model
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/robertkrimen/otto"
+	"gopkg.in/guregu/null.v4"
+)
+
+const defaultSocketTimeout = 10 * time.Second
+
+// SocketPayload is the data sent by tcp and udp steps
+type SocketPayload struct {
+	Text   null.String            `yaml:"text"`
+	Hex    null.String            `yaml:"hex"`
+	Base64 null.String            `yaml:"base64"`
+	Expr   ExecutableStringOrNull `yaml:"expr"`
+}
+
+// SocketRead defines when a reply is complete, without delimiter or length
+// everything received until the timeout or the end of the connection is read
+type SocketRead struct {
+	Delimiter null.String `yaml:"delimiter"`
+	Length    null.Int    `yaml:"length"`
+	Timeout   null.String `yaml:"timeout"`
+}
+
+type SocketAssertion struct {
+	Name     null.String            `yaml:"name"`
+	Contains null.String            `yaml:"contains"`
+	Expr     ExecutableStringOrNull `yaml:"expr"`
+}
+
+// socketConnection is a tcp or udp connection, which can be kept open per virtual user
+type socketConnection struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func (s *socketConnection) Close() error {
+	return s.conn.Close()
+}
+
+func newSocketConnection(conn net.Conn) *socketConnection {
+	return &socketConnection{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+	}
+}
+
+func (s *SocketPayload) Validate() error {
+	count := 0
+
+	for _, isSet := range []bool{
+		s.Text.Valid,
+		s.Hex.Valid,
+		s.Base64.Valid,
+		s.Expr.Valid,
+	} {
+		if isSet {
+			count++
+		}
+	}
+
+	if count != 1 {
+		return fmt.Errorf("send must contain one child of 'text' | 'hex' | 'base64' | 'expr'")
+	}
+
+	return nil
+}
+
+func (s *SocketPayload) build(ctx context.Context, vm *otto.Otto) ([]byte, error) {
+	switch {
+	case s.Text.Valid:
+		text, err := interpolateString(vm, s.Text.String)
+		if err != nil {
+			return nil, fmt.Errorf("error in 'text': %s", err)
+		}
+
+		return []byte(text), nil
+	case s.Hex.Valid:
+		value, err := interpolateString(vm, s.Hex.String)
+		if err != nil {
+			return nil, fmt.Errorf("error in 'hex': %s", err)
+		}
+
+		data, err := hex.DecodeString(strings.Join(strings.Fields(value), ""))
+		if err != nil {
+			return nil, fmt.Errorf("can't decode 'hex': %s", err)
+		}
+
+		return data, nil
+	case s.Base64.Valid:
+		value, err := interpolateString(vm, s.Base64.String)
+		if err != nil {
+			return nil, fmt.Errorf("error in 'base64': %s", err)
+		}
+
+		data, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("can't decode 'base64': %s", err)
+		}
+
+		return data, nil
+	default:
+		val, err := s.Expr.Execute(ctx, vm)
+		if err != nil {
+			return nil, fmt.Errorf("error executing 'expr': %s", err)
+		}
+
+		strVal, err := val.ToString()
+		if err != nil {
+			return nil, fmt.Errorf("'expr' must return a string: %s", err)
+		}
+
+		return []byte(strVal), nil
+	}
+}
+
+func (s *SocketRead) Validate() error {
+	if s.Delimiter.Valid && s.Length.Valid {
+		return fmt.Errorf("read must contain only one of 'delimiter' | 'length'")
+	}
+
+	if s.Delimiter.Valid && s.Delimiter.String == "" {
+		return fmt.Errorf("'delimiter' must not be empty")
+	}
+
+	if s.Length.Valid && s.Length.Int64 < 1 {
+		return fmt.Errorf("'length' must be greater than 0")
+	}
+
+	if s.Timeout.Valid {
+		_, err := parseDurationOrNull("timeout", s.Timeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SocketRead) timeout() time.Duration {
+	if !s.Timeout.Valid {
+		return defaultSocketTimeout
+	}
+
+	timeout, err := parseDurationOrNull("timeout", s.Timeout)
+	if err != nil {
+		return defaultSocketTimeout
+	}
+
+	return timeout
+}
+
+// interruptOnCancel sets a deadline in the past once the context is cancelled, so blocking
+// reads or writes return immediately instead of waiting for their timeout, call stop when done
+func interruptOnCancel(ctx context.Context, setDeadline func(time.Time) error) (stop func() bool) {
+	return context.AfterFunc(ctx, func() {
+		setDeadline(time.Now())
+	})
+}
+
+// read reads the reply of a stream connection, the delimiter isn't part of the reply,
+// timedOut is true if reading everything until the timeout ended with the timeout
+func (s *SocketRead) read(ctx context.Context, connection *socketConnection) (reply []byte, timedOut bool, err error) {
+	deadline := time.Now().Add(s.timeout())
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	err = connection.conn.SetReadDeadline(deadline)
+	if err != nil {
+		return nil, false, fmt.Errorf("can't set read deadline: %s", err)
+	}
+
+	stop := interruptOnCancel(ctx, connection.conn.SetReadDeadline)
+	defer stop()
+
+	switch {
+	case s.Delimiter.Valid:
+		delimiter := []byte(s.Delimiter.String)
+		data := []byte{}
+
+		for !bytes.HasSuffix(data, delimiter) {
+			b, err := connection.reader.ReadByte()
+			if err != nil {
+				return data, false, fmt.Errorf("can't read until delimiter: %s", err)
+			}
+
+			data = append(data, b)
+
+			if int64(len(data)) > defaultMaxResponseBodySize {
+				return data, false, fmt.Errorf("delimiter not found within %d bytes", defaultMaxResponseBodySize)
+			}
+		}
+
+		return data[:len(data)-len(delimiter)], false, nil
+	case s.Length.Valid:
+		data := make([]byte, s.Length.Int64)
+
+		n, err := io.ReadFull(connection.reader, data)
+		if err != nil {
+			return data[:n], false, fmt.Errorf("can't read %d bytes: %s", s.Length.Int64, err)
+		}
+
+		return data, false, nil
+	default:
+		buffer := &cappedBuffer{max: defaultMaxResponseBodySize}
+
+		_, err := io.Copy(buffer, connection.reader)
+
+		if ctx.Err() != nil {
+			return buffer.Bytes(), false, ctx.Err()
+		}
+
+		var netErr net.Error
+		if err != nil && errors.As(err, &netErr) && netErr.Timeout() {
+			return buffer.Bytes(), true, nil
+		}
+
+		if err != nil {
+			return buffer.Bytes(), false, fmt.Errorf("can't read: %s", err)
+		}
+
+		return buffer.Bytes(), false, nil
+	}
+}
+
+func (s *SocketAssertion) Verify(ctx context.Context, reply []byte, vm *otto.Otto) error {
+	name := assertionName(s.Name)
+
+	if s.Contains.Valid && !bytes.Contains(reply, []byte(s.Contains.String)) {
+		return fmt.Errorf("assertion %son reply failed: expected to contain '%s'", name, s.Contains.String)
+	}
+
+	return verifyExprAssertion(ctx, s.Name, s.Expr, vm)
+}
+
+// assignSocketReply sets 'response' with the reply as text, hex and base64 in the vm
+func assignSocketReply(ctx context.Context, reply []byte, vm *otto.Otto) error {
+	return assignVariable(ctx, vm, "response", map[string]interface{}{
+		"text":   string(reply),
+		"hex":    hex.EncodeToString(reply),
+		"base64": base64.StdEncoding.EncodeToString(reply),
+		"length": len(reply),
+	})
+}
+
+// socketStep contains the options shared by tcp and udp steps
+type socketStep struct {
+	Address        string            `yaml:"address"`
+	Connection     null.String       `yaml:"connection"`
+	ConnectTimeout null.String       `yaml:"connect_timeout"`
+	Send           *SocketPayload    `yaml:"send"`
+	Read           *SocketRead       `yaml:"read"`
+	Close          null.Bool         `yaml:"close"`
+	Assertions     []SocketAssertion `yaml:"assertions"`
+}
+
+type socketDialFunc func(ctx context.Context, address string, timeout time.Duration) (net.Conn, error)
+type socketReadFunc func(ctx context.Context, connection *socketConnection) (reply []byte, timedOut bool, err error)
+
+func (s *socketStep) validate() error {
+	if s.Address == "" && !s.Connection.Valid {
+		return fmt.Errorf("missing 'address'")
+	}
+
+	if s.ConnectTimeout.Valid {
+		_, err := parseDurationOrNull("connect_timeout", s.ConnectTimeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	if s.Send != nil {
+		err := s.Send.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid send: %s", err)
+		}
+	}
+
+	if s.Read != nil {
+		err := s.Read.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid read: %s", err)
+		}
+	}
+
+	if s.Read == nil && len(s.Assertions) > 0 {
+		return fmt.Errorf("assertions require 'read'")
+	}
+
+	return nil
+}
+
+// execute connects (or reuses the named connection of the virtual user), sends and reads the reply
+func (s *socketStep) execute(ctx context.Context, network string, vm *otto.Otto, dial socketDialFunc, read socketReadFunc) (*StepExecutionStats, error) {
+	stepStats := &StepExecutionStats{}
+
+	virtualUser := virtualUserFromContext(ctx)
+	if virtualUser == nil {
+		return stepStats, fmt.Errorf("%s steps must run within a load test", network)
+	}
+
+	key := ""
+	if s.Connection.Valid {
+		key = network + ":" + s.Connection.String
+	}
+
+	var connection *socketConnection
+	if key != "" {
+		connection, _ = virtualUser.connection(key).(*socketConnection)
+	}
+
+	if connection == nil {
+		if s.Address == "" {
+			return stepStats, fmt.Errorf("%s connection isn't open and no 'address' is given", network)
+		}
+
+		address, err := interpolateString(vm, s.Address)
+		if err != nil {
+			return stepStats, fmt.Errorf("error in 'address': %s", err)
+		}
+
+		timeout := defaultSocketTimeout
+		if s.ConnectTimeout.Valid {
+			timeout, err = parseDurationOrNull("connect_timeout", s.ConnectTimeout)
+			if err != nil {
+				return stepStats, err
+			}
+		}
+
+		start := time.Now()
+
+		conn, err := dial(ctx, address, timeout)
+
+		durationConnect := time.Since(start)
+		stepStats.DurationConnect = &durationConnect
+
+		if err != nil {
+			return stepStats, fmt.Errorf("can't connect: %s", err)
+		}
+
+		connection = newSocketConnection(conn)
+
+		if key != "" {
+			virtualUser.setConnection(key, connection)
+		}
+	}
+
+	// Unnamed connections only live for one step, named ones are closed on errors
+	closeConnection := key == "" || (s.Close.Valid && s.Close.Bool)
+
+	defer func() {
+		if !closeConnection {
+			return
+		}
+
+		connection.Close()
+
+		if key != "" {
+			virtualUser.removeConnection(key)
+		}
+	}()
+
+	start := time.Now()
+
+	if s.Send != nil {
+		data, err := s.Send.build(ctx, vm)
+		if err != nil {
+			return stepStats, fmt.Errorf("error in 'send': %s", err)
+		}
+
+		// A peer not reading would otherwise block the write forever
+		err = connection.conn.SetWriteDeadline(time.Now().Add(defaultSocketTimeout))
+		if err != nil {
+			closeConnection = true
+
+			return stepStats, fmt.Errorf("can't set write deadline: %s", err)
+		}
+
+		stop := interruptOnCancel(ctx, connection.conn.SetWriteDeadline)
+		n, err := connection.conn.Write(data)
+		stop()
+
+		stepStats.BytesSent.Scan(int64(n))
+
+		if err != nil {
+			closeConnection = true
+
+			if ctx.Err() != nil {
+				return stepStats, ctx.Err()
+			}
+
+			return stepStats, fmt.Errorf("can't send: %s", err)
+		}
+	}
+
+	if s.Read == nil {
+		return stepStats, nil
+	}
+
+	reply, timedOut, err := read(ctx, connection)
+
+	stepStats.BytesReceived.Scan(int64(len(reply)))
+
+	if err != nil {
+		closeConnection = true
+
+		if ctx.Err() != nil {
+			return stepStats, ctx.Err()
+		}
+
+		return stepStats, err
+	}
+
+	// The duration of a read ending with the timeout only measures the timeout
+	if !timedOut {
+		durationRoundTrip := time.Since(start)
+		stepStats.DurationRoundTrip = &durationRoundTrip
+	}
+
+	err = assignSocketReply(ctx, reply, vm)
+	if err != nil {
+		return stepStats, err
+	}
+
+	for _, assertion := range s.Assertions {
+		err = assertion.Verify(ctx, reply, vm)
+		if err != nil {
+			return stepStats, err
+		}
+	}
+
+	return stepStats, nil
+}
@@ -0,0 +1,179 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robertkrimen/otto"
+	"gopkg.in/guregu/null.v4"
+
+	// Registers the 'mysql', 'pgx' (postgres) and 'sqlite' drivers,
+	// sqlite works without cgo
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+)
+
+const contextKeySqlDatabases contextKey = "sql_databases"
+
+const defaultSqlDatabase = "default"
+
+// SqlDatabaseConfig defines a connection pool shared by all virtual users of a load test
+type SqlDatabaseConfig struct {
+	Driver          string      `yaml:"driver"`
+	DSN             string      `yaml:"dsn"`
+	MaxOpenConns    null.Int    `yaml:"max_open_conns"`
+	MaxIdleConns    null.Int    `yaml:"max_idle_conns"`
+	ConnMaxLifetime null.String `yaml:"conn_max_lifetime"`
+}
+
+func (s *SqlDatabaseConfig) Validate() error {
+	if s.Driver == "" {
+		return fmt.Errorf("missing 'driver'")
+	}
+
+	found := false
+
+	for _, driver := range sql.Drivers() {
+		if driver == s.Driver {
+			found = true
+
+			break
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("unknown driver '%s', must be one of '%s'", s.Driver, strings.Join(sql.Drivers(), "' | '"))
+	}
+
+	if s.DSN == "" {
+		return fmt.Errorf("missing 'dsn'")
+	}
+
+	if s.MaxOpenConns.Valid && s.MaxOpenConns.Int64 < 0 {
+		return fmt.Errorf("'max_open_conns' must not be negative")
+	}
+
+	if s.MaxIdleConns.Valid && s.MaxIdleConns.Int64 < 0 {
+		return fmt.Errorf("'max_idle_conns' must not be negative")
+	}
+
+	if s.ConnMaxLifetime.Valid {
+		_, err := parseDurationOrNull("conn_max_lifetime", s.ConnMaxLifetime)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SqlDatabaseConfig) open(vm *otto.Otto) (*sql.DB, error) {
+	dsn, err := interpolateString(vm, s.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("error in 'dsn': %s", err)
+	}
+
+	db, err := sql.Open(s.Driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.MaxOpenConns.Valid {
+		db.SetMaxOpenConns(int(s.MaxOpenConns.Int64))
+	}
+
+	if s.MaxIdleConns.Valid {
+		db.SetMaxIdleConns(int(s.MaxIdleConns.Int64))
+	}
+
+	if s.ConnMaxLifetime.Valid {
+		connMaxLifetime, err := parseDurationOrNull("conn_max_lifetime", s.ConnMaxLifetime)
+		if err != nil {
+			db.Close()
+
+			return nil, err
+		}
+
+		db.SetConnMaxLifetime(connMaxLifetime)
+	}
+
+	return db, nil
+}
+
+// sqlDatabases holds the pools of a load test, which are opened on first use
+type sqlDatabases struct {
+	configs map[string]*SqlDatabaseConfig
+	mutex   sync.Mutex
+	dbs     map[string]*sql.DB
+}
+
+func (s *sqlDatabases) database(name string, vm *otto.Otto) (*sql.DB, *SqlDatabaseConfig, error) {
+	config, ok := s.configs[name]
+	if !ok {
+		return nil, nil, fmt.Errorf("database '%s' is not defined in 'databases' of the load test", name)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if db, ok := s.dbs[name]; ok {
+		return db, config, nil
+	}
+
+	db, err := config.open(vm)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't open database '%s': %s", name, err)
+	}
+
+	s.dbs[name] = db
+
+	return db, config, nil
+}
+
+// Close closes all opened pools
+func (s *sqlDatabases) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for name, db := range s.dbs {
+		db.Close()
+		delete(s.dbs, name)
+	}
+}
+
+func newSqlDatabases(configs map[string]*SqlDatabaseConfig) *sqlDatabases {
+	return &sqlDatabases{
+		configs: configs,
+		dbs:     map[string]*sql.DB{},
+	}
+}
+
+func contextWithSqlDatabases(ctx context.Context, databases *sqlDatabases) context.Context {
+	return context.WithValue(ctx, contextKeySqlDatabases, databases)
+}
+
+func sqlDatabasesFromContext(ctx context.Context) *sqlDatabases {
+	databases, ok := ctx.Value(contextKeySqlDatabases).(*sqlDatabases)
+	if !ok {
+		return nil
+	}
+
+	return databases
+}
+
+// sqlValue converts a scanned column value so it can be encoded as json
+func sqlValue(value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case []byte:
+		return string(typedValue)
+	case time.Time:
+		return typedValue.Format(time.RFC3339Nano)
+	default:
+		return typedValue
+	}
+}
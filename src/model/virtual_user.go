@@ -0,0 +1,142 @@
+package model
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/robertkrimen/otto"
+)
+
+type contextKey string
+
+const contextKeyVirtualUser contextKey = "virtual_user"
+
+// VirtualUser holds the state of one simulated user,
+// which is either a load test itself or one of its threads
+type VirtualUser struct {
+	httpClientConfig *HttpClientConfig
+	httpClient       *http.Client
+	mutex            sync.Mutex
+	oauth2Tokens     map[string]*oauth2Token
+	digestChallenges map[string]*digestChallenge
+	connections      map[string]io.Closer
+}
+
+// HttpClientConfig returns the http client config used by the virtual user
+func (v *VirtualUser) HttpClientConfig() *HttpClientConfig {
+	return v.httpClientConfig
+}
+
+// HttpClient returns the http client of the virtual user
+func (v *VirtualUser) HttpClient() *http.Client {
+	return v.httpClient
+}
+
+// StartIteration is called at the beginning of each loop iteration
+func (v *VirtualUser) StartIteration() {
+	if v.httpClientConfig.NewConnectionPerIteration.Valid && v.httpClientConfig.NewConnectionPerIteration.Bool {
+		v.httpClient.CloseIdleConnections()
+	}
+}
+
+func (v *VirtualUser) oauth2Token(key string) *oauth2Token {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	return v.oauth2Tokens[key]
+}
+
+func (v *VirtualUser) setOAuth2Token(key string, token *oauth2Token) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	v.oauth2Tokens[key] = token
+}
+
+// withDigestChallenge calls fn with the cached digest challenge if there is one
+func (v *VirtualUser) withDigestChallenge(key string, fn func(challenge *digestChallenge) error) error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	challenge, ok := v.digestChallenges[key]
+	if !ok {
+		return nil
+	}
+
+	return fn(challenge)
+}
+
+func (v *VirtualUser) setDigestChallenge(key string, challenge *digestChallenge) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	v.digestChallenges[key] = challenge
+}
+
+// connection returns a connection kept open across steps (e.g. a websocket) or nil
+func (v *VirtualUser) connection(key string) io.Closer {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	return v.connections[key]
+}
+
+func (v *VirtualUser) setConnection(key string, conn io.Closer) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	v.connections[key] = conn
+}
+
+func (v *VirtualUser) removeConnection(key string) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	delete(v.connections, key)
+}
+
+// Close releases all resources held by the virtual user
+func (v *VirtualUser) Close() {
+	v.mutex.Lock()
+	for key, conn := range v.connections {
+		conn.Close()
+		delete(v.connections, key)
+	}
+	v.mutex.Unlock()
+
+	v.httpClient.CloseIdleConnections()
+}
+
+func NewVirtualUser(ctx context.Context, httpClientConfig *HttpClientConfig, vm *otto.Otto) (*VirtualUser, error) {
+	if httpClientConfig == nil {
+		httpClientConfig = &HttpClientConfig{}
+	}
+
+	httpClient, err := httpClientConfig.NewClient(ctx, vm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &VirtualUser{
+		httpClientConfig: httpClientConfig,
+		httpClient:       httpClient,
+		oauth2Tokens:     map[string]*oauth2Token{},
+		digestChallenges: map[string]*digestChallenge{},
+		connections:      map[string]io.Closer{},
+	}, nil
+}
+
+func contextWithVirtualUser(ctx context.Context, virtualUser *VirtualUser) context.Context {
+	return context.WithValue(ctx, contextKeyVirtualUser, virtualUser)
+}
+
+func virtualUserFromContext(ctx context.Context) *VirtualUser {
+	virtualUser, ok := ctx.Value(contextKeyVirtualUser).(*VirtualUser)
+	if !ok {
+		return nil
+	}
+
+	return virtualUser
+}
@@ -1,5 +1,92 @@
 package model
 
-import "sync"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/robertkrimen/otto"
+)
 
 var mutexVm = sync.Mutex{}
+
+// errVmInterrupted is raised as panic within the vm to stop a running script
+var errVmInterrupted = errors.New("script interrupted")
+
+// runScript runs the script in the vm and interrupts it once the context is cancelled,
+// e.g. endless loops in scripts stop on signals or the timeout of the run,
+// mutexVm must be held by the caller
+func runScript(ctx context.Context, vm *otto.Otto, src string) (value otto.Value, err error) {
+	interrupt := make(chan func(), 1)
+	vm.Interrupt = interrupt
+
+	stop := context.AfterFunc(ctx, func() {
+		interrupt <- func() {
+			panic(errVmInterrupted)
+		}
+	})
+
+	defer func() {
+		stop()
+		vm.Interrupt = nil
+
+		caught := recover()
+		if caught == nil {
+			return
+		}
+
+		if caught != errVmInterrupted {
+			panic(caught)
+		}
+
+		value = otto.UndefinedValue()
+		err = ctx.Err()
+	}()
+
+	return vm.Run(src)
+}
+
+// assignVariable sets the variable to the json encoded value in the vm
+func assignVariable(ctx context.Context, vm *otto.Otto, name string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("can't encode %s: %s", name, err)
+	}
+
+	mutexVm.Lock()
+	defer mutexVm.Unlock()
+
+	_, err = runScript(ctx, vm, name+" = "+string(data))
+	if err != nil {
+		return fmt.Errorf("can't assign %s: %s", name, err)
+	}
+
+	return nil
+}
+
+// matchesUntil sets the received value as variable in the vm and evaluates 'until',
+// without 'until' every value matches
+func matchesUntil(ctx context.Context, vm *otto.Otto, name string, value interface{}, until ExecutableStringOrNull) (bool, error) {
+	err := assignVariable(ctx, vm, name, value)
+	if err != nil {
+		return false, err
+	}
+
+	if !until.Valid {
+		return true, nil
+	}
+
+	val, err := until.Execute(ctx, vm)
+	if err != nil {
+		return false, fmt.Errorf("error executing 'until': %s", err)
+	}
+
+	matched, err := val.ToBoolean()
+	if err != nil {
+		return false, fmt.Errorf("'until' must return a boolean: %s", err)
+	}
+
+	return matched, nil
+}
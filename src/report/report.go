@@ -21,48 +21,119 @@ type ReportDataJSONStepExecution struct {
 	DurationTotal int64 `json:"duration_total"`
 }
 
+type ReportDataJSONStepPhases struct {
+	DNS      float64 `json:"dns"`
+	Connect  float64 `json:"connect"`
+	TLS      float64 `json:"tls"`
+	TTFB     float64 `json:"ttfb"`
+	Transfer float64 `json:"transfer"`
+}
+
 type ReportDataJSONStep struct {
 	Name       string                         `json:"name"`
+	Phases     *ReportDataJSONStepPhases      `json:"phases,omitempty"`
 	Executions []*ReportDataJSONStepExecution `json:"executions"`
 }
 
 type ReportDataJSON struct {
-	Steps []*ReportDataJSONStep `json:"steps"`
+	Interrupted bool                  `json:"interrupted"`
+	Steps       []*ReportDataJSONStep `json:"steps"`
 }
 
 type ReportData struct {
 	Datetime           string
 	DurationTotal      time.Duration
+	Interrupted        bool
 	CountStepsTotal    int64
 	CountStepsSkipped  int64
 	CountStepsSucceded int64
 	CountStepsFailed   int64
+	CountStepsRetried  int64
+	MessagesLost       null.Int
+	DurationPause      time.Duration
 	Steps              []*ReportDataStep
+	BranchSteps        []*ReportDataBranchStep
+	OperationSteps     []*ReportDataOperationStep
 	ExecutionsJSON     string
 }
 
+type ReportDataBranch struct {
+	Name          string
+	CountSelected int64
+	Weight        float64
+	Share         float64
+}
+
+type ReportDataBranchStep struct {
+	Name     string
+	Branches []*ReportDataBranch
+}
+
+type ReportDataOperation struct {
+	Name        string
+	Count       int64
+	DurationMin time.Duration
+	DurationAvg time.Duration
+	DurationMax time.Duration
+}
+
+type ReportDataOperationStep struct {
+	Name       string
+	Operations []*ReportDataOperation
+}
+
 type ReportDataStepCode struct {
 	Code  string
 	Count int
 }
 
+type ReportDataStepProtocol struct {
+	Protocol string
+	Count    int
+}
+
 type ReportDataStep struct {
-	Name             string
-	CountTotal       int64
-	CountSkipped     int64
-	CountSucceded    int64
-	CountFailed      int64
-	Errors           []string
-	Codes            []*ReportDataStepCode
-	DurationAvg      time.Duration
-	DurationMin      time.Duration
-	DurationMax      time.Duration
-	BytesSentAvg     null.Float
-	BytesSentMin     null.Int
-	BytesSentMax     null.Int
-	BytesReceivedAvg null.Float
-	BytesReceivedMin null.Int
-	BytesReceivedMax null.Int
+	Name                     string
+	CountTotal               int64
+	CountSkipped             int64
+	CountSucceded            int64
+	CountFailed              int64
+	CountRetried             int64
+	Errors                   []string
+	Codes                    []*ReportDataStepCode
+	Protocols                []*ReportDataStepProtocol
+	DurationAvg              time.Duration
+	DurationMin              time.Duration
+	DurationMax              time.Duration
+	DurationPauseSum         *time.Duration
+	DurationPauseAvg         *time.Duration
+	DurationDNSAvg           *time.Duration
+	DurationConnectAvg       *time.Duration
+	DurationTLSAvg           *time.Duration
+	DurationTTFBAvg          *time.Duration
+	DurationTransferAvg      *time.Duration
+	DurationRoundTripAvg     *time.Duration
+	DurationEndToEndMin      *time.Duration
+	DurationEndToEndAvg      *time.Duration
+	DurationEndToEndMax      *time.Duration
+	DurationFirstEventAvg    *time.Duration
+	DurationBetweenEventsMin *time.Duration
+	DurationBetweenEventsAvg *time.Duration
+	DurationBetweenEventsMax *time.Duration
+	CountConnectionReused    int64
+	CountConnectionNew       int64
+	BytesSentAvg             null.Float
+	BytesSentMin             null.Int
+	BytesSentMax             null.Int
+	BytesReceivedAvg         null.Float
+	BytesReceivedMin         null.Int
+	BytesReceivedMax         null.Int
+	BytesReceivedBodyAvg     null.Float
+	MessagesSent             null.Int
+	MessagesReceived         null.Int
+	MessagesDuplicate        null.Int
+	MessagesDropped          null.Int
+	Rows                     null.Int
 }
 
 func (r *Report) Generate(runStats *stats.RunStats) ([]byte, error) {
@@ -85,15 +156,69 @@ func (r *Report) Generate(runStats *stats.RunStats) ([]byte, error) {
 	data := &ReportData{}
 	data.Datetime = runStats.StartTime.Format("2006-01-02 15:04:05")
 	data.DurationTotal = runStats.TotalDuration
+	data.Interrupted = runStats.Interrupted
 	data.CountStepsTotal = runStats.CountStepsTotal
 	data.CountStepsSkipped = runStats.CountStepsSkipped
 	data.CountStepsSucceded = runStats.CountStepsSucceded
 	data.CountStepsFailed = runStats.CountStepsFailed
+	data.CountStepsRetried = runStats.CountStepsRetried
+	data.MessagesLost = runStats.MessagesLost
+	data.DurationPause = runStats.DurationPause
 	data.Steps = []*ReportDataStep{}
 
 	dataJSON := &ReportDataJSON{}
+	dataJSON.Interrupted = runStats.Interrupted
 	dataJSON.Steps = []*ReportDataJSONStep{}
 
+	data.BranchSteps = []*ReportDataBranchStep{}
+
+	for name, runStatStep := range runStats.Steps {
+		if !runStatStep.HasExplicitName || len(runStatStep.Branches) == 0 {
+			continue
+		}
+
+		branchStep := &ReportDataBranchStep{}
+		branchStep.Name = name
+		branchStep.Branches = []*ReportDataBranch{}
+
+		for branchName, runStatBranch := range runStatStep.Branches {
+			branch := &ReportDataBranch{}
+			branch.Name = branchName
+			branch.CountSelected = runStatBranch.CountSelected
+			branch.Weight = runStatBranch.Weight * 100
+			branch.Share = runStatBranch.Share * 100
+
+			branchStep.Branches = append(branchStep.Branches, branch)
+		}
+
+		data.BranchSteps = append(data.BranchSteps, branchStep)
+	}
+
+	data.OperationSteps = []*ReportDataOperationStep{}
+
+	for name, runStatStep := range runStats.Steps {
+		if !runStatStep.HasExplicitName || len(runStatStep.Operations) == 0 {
+			continue
+		}
+
+		operationStep := &ReportDataOperationStep{}
+		operationStep.Name = name
+		operationStep.Operations = []*ReportDataOperation{}
+
+		for operationName, runStatOperation := range runStatStep.Operations {
+			operation := &ReportDataOperation{}
+			operation.Name = operationName
+			operation.Count = runStatOperation.Count
+			operation.DurationMin = runStatOperation.DurationMin
+			operation.DurationAvg = runStatOperation.DurationAvg
+			operation.DurationMax = runStatOperation.DurationMax
+
+			operationStep.Operations = append(operationStep.Operations, operation)
+		}
+
+		data.OperationSteps = append(data.OperationSteps, operationStep)
+	}
+
 	for name, runStatStep := range runStats.Steps {
 		if runStatStep.IsGroup || !runStatStep.HasExplicitName {
 			continue
@@ -105,9 +230,27 @@ func (r *Report) Generate(runStats *stats.RunStats) ([]byte, error) {
 		step.CountSkipped = runStatStep.CountSkipped
 		step.CountSucceded = runStatStep.CountSucceded
 		step.CountFailed = runStatStep.CountFailed
+		step.CountRetried = runStatStep.CountRetried
 		step.DurationAvg = runStatStep.DurationAvg
 		step.DurationMin = runStatStep.DurationMin
 		step.DurationMax = runStatStep.DurationMax
+		step.DurationPauseSum = runStatStep.DurationPauseSum
+		step.DurationPauseAvg = runStatStep.DurationPauseAvg
+		step.DurationDNSAvg = runStatStep.DurationDNSAvg
+		step.DurationConnectAvg = runStatStep.DurationConnectAvg
+		step.DurationTLSAvg = runStatStep.DurationTLSAvg
+		step.DurationTTFBAvg = runStatStep.DurationTTFBAvg
+		step.DurationTransferAvg = runStatStep.DurationTransferAvg
+		step.DurationRoundTripAvg = runStatStep.DurationRoundTripAvg
+		step.DurationEndToEndMin = runStatStep.DurationEndToEndMin
+		step.DurationEndToEndAvg = runStatStep.DurationEndToEndAvg
+		step.DurationEndToEndMax = runStatStep.DurationEndToEndMax
+		step.DurationFirstEventAvg = runStatStep.DurationFirstEventAvg
+		step.DurationBetweenEventsMin = runStatStep.DurationBetweenEventsMin
+		step.DurationBetweenEventsAvg = runStatStep.DurationBetweenEventsAvg
+		step.DurationBetweenEventsMax = runStatStep.DurationBetweenEventsMax
+		step.CountConnectionReused = runStatStep.CountConnectionReused
+		step.CountConnectionNew = runStatStep.CountConnectionNew
 
 		step.BytesSentAvg = runStatStep.BytesSentAvg
 		step.BytesSentMin = runStatStep.BytesSentMin
@@ -115,6 +258,12 @@ func (r *Report) Generate(runStats *stats.RunStats) ([]byte, error) {
 		step.BytesReceivedAvg = runStatStep.BytesReceivedAvg
 		step.BytesReceivedMin = runStatStep.BytesReceivedMin
 		step.BytesReceivedMax = runStatStep.BytesReceivedMax
+		step.BytesReceivedBodyAvg = runStatStep.BytesReceivedBodyAvg
+		step.MessagesSent = runStatStep.MessagesSent
+		step.MessagesReceived = runStatStep.MessagesReceived
+		step.MessagesDuplicate = runStatStep.MessagesDuplicate
+		step.MessagesDropped = runStatStep.MessagesDropped
+		step.Rows = runStatStep.Rows
 
 		step.Codes = []*ReportDataStepCode{}
 		for code, count := range runStatStep.Codes {
@@ -126,6 +275,16 @@ func (r *Report) Generate(runStats *stats.RunStats) ([]byte, error) {
 			step.Codes = append(step.Codes, stepCode)
 		}
 
+		step.Protocols = []*ReportDataStepProtocol{}
+		for protocol, count := range runStatStep.Protocols {
+			stepProtocol := &ReportDataStepProtocol{}
+
+			stepProtocol.Protocol = protocol
+			stepProtocol.Count = count
+
+			step.Protocols = append(step.Protocols, stepProtocol)
+		}
+
 		mapErrors := map[string]bool{}
 		for _, err := range runStatStep.Errors {
 			mapErrors[err.Error()] = true
@@ -139,6 +298,15 @@ func (r *Report) Generate(runStats *stats.RunStats) ([]byte, error) {
 		dataJSONStep := &ReportDataJSONStep{}
 		dataJSONStep.Name = name
 
+		if runStatStep.DurationTTFBAvg != nil {
+			dataJSONStep.Phases = &ReportDataJSONStepPhases{}
+			dataJSONStep.Phases.DNS = durationMilliseconds(runStatStep.DurationDNSAvg)
+			dataJSONStep.Phases.Connect = durationMilliseconds(runStatStep.DurationConnectAvg)
+			dataJSONStep.Phases.TLS = durationMilliseconds(runStatStep.DurationTLSAvg)
+			dataJSONStep.Phases.TTFB = durationMilliseconds(runStatStep.DurationTTFBAvg)
+			dataJSONStep.Phases.Transfer = durationMilliseconds(runStatStep.DurationTransferAvg)
+		}
+
 		for _, runStatExecution := range runStatStep.Executions {
 			dataJSONExecution := &ReportDataJSONStepExecution{}
 
@@ -169,6 +337,14 @@ func (r *Report) Generate(runStats *stats.RunStats) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+func durationMilliseconds(duration *time.Duration) float64 {
+	if duration == nil {
+		return 0
+	}
+
+	return float64(duration.Microseconds()) / 1000
+}
+
 func NewReport() *Report {
 	return &Report{}
 }
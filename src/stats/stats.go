@@ -16,43 +16,194 @@ const (
 	StepExecutionStatusSuccess StepExecutionStatus = "success"
 	StepExecutionStatusFailed  StepExecutionStatus = "failed"
 	StepExecutionStatusSkipped StepExecutionStatus = "skipped"
+	StepExecutionStatusRetried StepExecutionStatus = "retried"
 )
 
 type StepExecution struct {
-	Name             string
-	HasExplicitName  bool
-	StartTime        time.Time
-	EndTime          time.Time
-	IsGroup          bool
-	DurationTotal    time.Duration
-	DurationRequest  *time.Duration
-	DurationResponse *time.Duration
-	Status           StepExecutionStatus
-	Error            error
-	Code             null.String
-	BytesSent        null.Int
-	BytesReceived    null.Int
+	Name                   string
+	HasExplicitName        bool
+	StartTime              time.Time
+	EndTime                time.Time
+	IsGroup                bool
+	DurationTotal          time.Duration
+	DurationRequest        *time.Duration
+	DurationResponse       *time.Duration
+	DurationPause          *time.Duration
+	DurationDNS            *time.Duration
+	DurationConnect        *time.Duration
+	DurationTLS            *time.Duration
+	DurationTTFB           *time.Duration
+	DurationTransfer       *time.Duration
+	DurationRoundTrip      *time.Duration
+	ConnectionReused       null.Bool
+	Status                 StepExecutionStatus
+	Error                  error
+	Code                   null.String
+	Protocol               null.String
+	BytesSent              null.Int
+	BytesReceived          null.Int
+	BytesReceivedBody      null.Int
+	MessagesSent           null.Int
+	MessagesReceived       null.Int
+	MessagesDuplicate      null.Int
+	MessagesDropped        null.Int
+	Rows                   null.Int
+	Branch                 null.String
+	BranchWeight           null.Float
+	Operations             []*StepOperation
+	DurationsEndToEnd      []time.Duration
+	DurationFirstEvent     *time.Duration
+	DurationsBetweenEvents []time.Duration
+}
+
+// StepOperation is a single operation within a step execution (e.g. a redis command)
+type StepOperation struct {
+	Name     string
+	Duration time.Duration
+}
+
+type RunStatBranch struct {
+	Weight        float64
+	CountSelected int64
+	Share         float64
+}
+
+type RunStatOperation struct {
+	Count       int64
+	DurationMin time.Duration
+	DurationAvg time.Duration
+	DurationMax time.Duration
+	durationSum time.Duration
 }
 
 type RunStatStep struct {
-	HasExplicitName  bool
-	IsGroup          bool
-	CountTotal       int64
-	CountSkipped     int64
-	CountSucceded    int64
-	CountFailed      int64
-	Errors           []error
-	DurationAvg      time.Duration
-	DurationMin      time.Duration
-	DurationMax      time.Duration
-	BytesSentAvg     null.Float
-	BytesSentMin     null.Int
-	BytesSentMax     null.Int
-	BytesReceivedAvg null.Float
-	BytesReceivedMin null.Int
-	BytesReceivedMax null.Int
-	Codes            map[string]int
-	Executions       []*StepExecution
+	HasExplicitName          bool
+	IsGroup                  bool
+	CountTotal               int64
+	CountSkipped             int64
+	CountSucceded            int64
+	CountFailed              int64
+	CountRetried             int64
+	Errors                   []error
+	DurationAvg              time.Duration
+	DurationMin              time.Duration
+	DurationMax              time.Duration
+	DurationPauseSum         *time.Duration
+	DurationPauseAvg         *time.Duration
+	DurationDNSAvg           *time.Duration
+	DurationConnectAvg       *time.Duration
+	DurationTLSAvg           *time.Duration
+	DurationTTFBAvg          *time.Duration
+	DurationTransferAvg      *time.Duration
+	DurationRoundTripAvg     *time.Duration
+	DurationEndToEndMin      *time.Duration
+	DurationEndToEndAvg      *time.Duration
+	DurationEndToEndMax      *time.Duration
+	DurationFirstEventAvg    *time.Duration
+	DurationBetweenEventsMin *time.Duration
+	DurationBetweenEventsAvg *time.Duration
+	DurationBetweenEventsMax *time.Duration
+	CountConnectionReused    int64
+	CountConnectionNew       int64
+	BytesSentAvg             null.Float
+	BytesSentMin             null.Int
+	BytesSentMax             null.Int
+	BytesReceivedAvg         null.Float
+	BytesReceivedMin         null.Int
+	BytesReceivedMax         null.Int
+	BytesReceivedBodyAvg     null.Float
+	MessagesSent             null.Int
+	MessagesReceived         null.Int
+	MessagesDuplicate        null.Int
+	MessagesDropped          null.Int
+	Rows                     null.Int
+	Codes                    map[string]int
+	Protocols                map[string]int
+	Branches                 map[string]*RunStatBranch
+	Operations               map[string]*RunStatOperation
+	Executions               []*StepExecution
+}
+
+// durationAvg calculates the average of optional durations
+type durationAvg struct {
+	sum     time.Duration
+	count   int
+	skipped int
+}
+
+func (d *durationAvg) add(duration *time.Duration) {
+	if duration == nil {
+		return
+	}
+
+	d.sum += *duration
+	d.count++
+}
+
+// addPhase adds the duration of a request phase, phases skipped by a traced request
+// (dns, connect and tls on a reused connection) count as 0, so all phases are averaged
+// over the same requests
+func (d *durationAvg) addPhase(duration *time.Duration, traced bool) {
+	if duration == nil && traced {
+		d.skipped++
+
+		return
+	}
+
+	d.add(duration)
+}
+
+// avg returns the average or nil if no duration was added
+func (d *durationAvg) avg() *time.Duration {
+	if d.count == 0 {
+		return nil
+	}
+
+	avg := time.Duration(math.Round(float64(d.sum) / float64(d.count+d.skipped)))
+
+	return &avg
+}
+
+// durationRange calculates the minimum, average and maximum of durations
+type durationRange struct {
+	durationAvg
+	min time.Duration
+	max time.Duration
+}
+
+func (d *durationRange) add(duration time.Duration) {
+	if d.count == 0 {
+		d.min = duration
+	} else {
+		d.min = utils.MinDuration(d.min, duration)
+	}
+	d.max = utils.MaxDuration(d.max, duration)
+
+	d.durationAvg.add(&duration)
+}
+
+// values returns the minimum, average and maximum or nil if no duration was added
+func (d *durationRange) values() (*time.Duration, *time.Duration, *time.Duration) {
+	if d.count == 0 {
+		return nil, nil, nil
+	}
+
+	min := d.min
+	max := d.max
+
+	return &min, d.avg(), &max
+}
+
+type runStatStepPhases struct {
+	dns           durationAvg
+	connect       durationAvg
+	tls           durationAvg
+	ttfb          durationAvg
+	transfer      durationAvg
+	rtt           durationAvg
+	firstEvent    durationAvg
+	endToEnd      durationRange
+	betweenEvents durationRange
 }
 
 type RunStats struct {
@@ -61,10 +212,14 @@ type RunStats struct {
 
 	StartTime          time.Time
 	TotalDuration      time.Duration
+	Interrupted        bool
 	CountStepsTotal    int64
 	CountStepsSkipped  int64
 	CountStepsSucceded int64
 	CountStepsFailed   int64
+	CountStepsRetried  int64
+	MessagesLost       null.Int
+	DurationPause      time.Duration
 	Steps              map[string]*RunStatStep
 }
 
@@ -76,6 +231,11 @@ func (r *RunStats) SetEnd() {
 	r.TotalDuration = time.Since(r.StartTime)
 }
 
+// SetInterrupted marks the run as incomplete (e.g. cancelled by a signal or timeout)
+func (r *RunStats) SetInterrupted() {
+	r.Interrupted = true
+}
+
 func (r *RunStats) AddStepExecution(stepExecution *StepExecution) {
 	r.mutexStepExecutions.Lock()
 	defer r.mutexStepExecutions.Unlock()
@@ -83,11 +243,21 @@ func (r *RunStats) AddStepExecution(stepExecution *StepExecution) {
 	r.stepExecutions = append(r.stepExecutions, stepExecution)
 }
 
+// AddMessagesLost adds the number of published messages of a load test which weren't consumed
+func (r *RunStats) AddMessagesLost(count int64) {
+	r.mutexStepExecutions.Lock()
+	defer r.mutexStepExecutions.Unlock()
+
+	r.MessagesLost.Scan(r.MessagesLost.Int64 + count)
+}
+
 func (r *RunStats) Aggregate() {
 	r.CountStepsTotal = 0
 	r.CountStepsSkipped = 0
 	r.CountStepsSucceded = 0
 	r.CountStepsFailed = 0
+	r.CountStepsRetried = 0
+	r.DurationPause = 0
 	r.Steps = map[string]*RunStatStep{}
 
 	durationMinMap := map[string]time.Duration{}
@@ -95,6 +265,11 @@ func (r *RunStats) Aggregate() {
 	durationSumMap := map[string]time.Duration{}
 	durationCountMap := map[string]int{}
 
+	phasesMap := map[string]*runStatStepPhases{}
+
+	durationPauseSumMap := map[string]time.Duration{}
+	durationPauseCountMap := map[string]int{}
+
 	bytesSentMinMap := map[string]int64{}
 	bytesSentMaxMap := map[string]int64{}
 	bytesSentSumMap := map[string]int64{}
@@ -105,18 +280,25 @@ func (r *RunStats) Aggregate() {
 	bytesReceivedSumMap := map[string]int64{}
 	bytesReceivedCountMap := map[string]int{}
 
+	bytesReceivedBodySumMap := map[string]int64{}
+	bytesReceivedBodyCountMap := map[string]int{}
+
 	for _, stepExecution := range r.stepExecutions {
 		if _, ok := r.Steps[stepExecution.Name]; !ok {
 			r.Steps[stepExecution.Name] = &RunStatStep{}
 			r.Steps[stepExecution.Name].IsGroup = stepExecution.IsGroup
 			r.Steps[stepExecution.Name].HasExplicitName = stepExecution.HasExplicitName
 			r.Steps[stepExecution.Name].Codes = map[string]int{}
+			r.Steps[stepExecution.Name].Protocols = map[string]int{}
+			r.Steps[stepExecution.Name].Branches = map[string]*RunStatBranch{}
+			r.Steps[stepExecution.Name].Operations = map[string]*RunStatOperation{}
 		}
 
-		r.CountStepsTotal++
-		r.Steps[stepExecution.Name].CountTotal++
-
-		r.Steps[stepExecution.Name].Executions = append(r.Steps[stepExecution.Name].Executions, stepExecution)
+		// Retried attempts are counted separately and not part of the total
+		if stepExecution.Status != StepExecutionStatusRetried {
+			r.CountStepsTotal++
+			r.Steps[stepExecution.Name].CountTotal++
+		}
 
 		switch stepExecution.Status {
 		case StepExecutionStatusSuccess:
@@ -128,12 +310,22 @@ func (r *RunStats) Aggregate() {
 		case StepExecutionStatusFailed:
 			r.CountStepsFailed++
 			r.Steps[stepExecution.Name].CountFailed++
+		case StepExecutionStatusRetried:
+			r.CountStepsRetried++
+			r.Steps[stepExecution.Name].CountRetried++
 		}
 
 		if stepExecution.Error != nil {
 			r.Steps[stepExecution.Name].Errors = append(r.Steps[stepExecution.Name].Errors, stepExecution.Error)
 		}
 
+		// Failed attempts that were retried would skew the durations, codes and bytes of the step
+		if stepExecution.Status == StepExecutionStatusRetried {
+			continue
+		}
+
+		r.Steps[stepExecution.Name].Executions = append(r.Steps[stepExecution.Name].Executions, stepExecution)
+
 		if durationCountMap[stepExecution.Name] == 0 {
 			durationMinMap[stepExecution.Name] = stepExecution.DurationTotal
 		} else {
@@ -143,11 +335,46 @@ func (r *RunStats) Aggregate() {
 		durationSumMap[stepExecution.Name] += stepExecution.DurationTotal
 		durationCountMap[stepExecution.Name]++
 
+		if _, ok := phasesMap[stepExecution.Name]; !ok {
+			phasesMap[stepExecution.Name] = &runStatStepPhases{}
+		}
+
+		traced := stepExecution.DurationTTFB != nil
+
+		phasesMap[stepExecution.Name].dns.addPhase(stepExecution.DurationDNS, traced)
+		phasesMap[stepExecution.Name].connect.addPhase(stepExecution.DurationConnect, traced)
+		phasesMap[stepExecution.Name].tls.addPhase(stepExecution.DurationTLS, traced)
+		phasesMap[stepExecution.Name].ttfb.add(stepExecution.DurationTTFB)
+		phasesMap[stepExecution.Name].transfer.add(stepExecution.DurationTransfer)
+		phasesMap[stepExecution.Name].rtt.add(stepExecution.DurationRoundTrip)
+
+		phasesMap[stepExecution.Name].firstEvent.add(stepExecution.DurationFirstEvent)
+
+		for _, durationEndToEnd := range stepExecution.DurationsEndToEnd {
+			phasesMap[stepExecution.Name].endToEnd.add(durationEndToEnd)
+		}
+
+		for _, durationBetweenEvents := range stepExecution.DurationsBetweenEvents {
+			phasesMap[stepExecution.Name].betweenEvents.add(durationBetweenEvents)
+		}
+
+		if stepExecution.ConnectionReused.Valid && stepExecution.ConnectionReused.Bool {
+			r.Steps[stepExecution.Name].CountConnectionReused++
+		} else if stepExecution.ConnectionReused.Valid {
+			r.Steps[stepExecution.Name].CountConnectionNew++
+		}
+
+		if stepExecution.DurationPause != nil {
+			r.DurationPause += *stepExecution.DurationPause
+			durationPauseSumMap[stepExecution.Name] += *stepExecution.DurationPause
+			durationPauseCountMap[stepExecution.Name]++
+		}
+
 		if stepExecution.BytesSent.Valid {
 			if bytesSentCountMap[stepExecution.Name] == 0 {
 				bytesSentMinMap[stepExecution.Name] = stepExecution.BytesSent.Int64
 			} else {
-				bytesSentMinMap[stepExecution.Name] = utils.MinInt64(bytesSentMaxMap[stepExecution.Name], stepExecution.BytesSent.Int64)
+				bytesSentMinMap[stepExecution.Name] = utils.MinInt64(bytesSentMinMap[stepExecution.Name], stepExecution.BytesSent.Int64)
 			}
 			bytesSentMaxMap[stepExecution.Name] = utils.MaxInt64(bytesSentMaxMap[stepExecution.Name], stepExecution.BytesSent.Int64)
 			bytesSentSumMap[stepExecution.Name] += stepExecution.BytesSent.Int64
@@ -158,16 +385,81 @@ func (r *RunStats) Aggregate() {
 			if bytesReceivedCountMap[stepExecution.Name] == 0 {
 				bytesReceivedMinMap[stepExecution.Name] = stepExecution.BytesReceived.Int64
 			} else {
-				bytesReceivedMinMap[stepExecution.Name] = utils.MinInt64(bytesReceivedMaxMap[stepExecution.Name], stepExecution.BytesReceived.Int64)
+				bytesReceivedMinMap[stepExecution.Name] = utils.MinInt64(bytesReceivedMinMap[stepExecution.Name], stepExecution.BytesReceived.Int64)
 			}
 			bytesReceivedMaxMap[stepExecution.Name] = utils.MaxInt64(bytesReceivedMaxMap[stepExecution.Name], stepExecution.BytesReceived.Int64)
 			bytesReceivedSumMap[stepExecution.Name] += stepExecution.BytesReceived.Int64
 			bytesReceivedCountMap[stepExecution.Name]++
 		}
 
+		if stepExecution.BytesReceivedBody.Valid {
+			bytesReceivedBodySumMap[stepExecution.Name] += stepExecution.BytesReceivedBody.Int64
+			bytesReceivedBodyCountMap[stepExecution.Name]++
+		}
+
+		if stepExecution.MessagesSent.Valid {
+			r.Steps[stepExecution.Name].MessagesSent.Scan(r.Steps[stepExecution.Name].MessagesSent.Int64 + stepExecution.MessagesSent.Int64)
+		}
+
+		if stepExecution.MessagesReceived.Valid {
+			r.Steps[stepExecution.Name].MessagesReceived.Scan(r.Steps[stepExecution.Name].MessagesReceived.Int64 + stepExecution.MessagesReceived.Int64)
+		}
+
+		if stepExecution.MessagesDuplicate.Valid {
+			r.Steps[stepExecution.Name].MessagesDuplicate.Scan(r.Steps[stepExecution.Name].MessagesDuplicate.Int64 + stepExecution.MessagesDuplicate.Int64)
+		}
+
+		if stepExecution.MessagesDropped.Valid {
+			r.Steps[stepExecution.Name].MessagesDropped.Scan(r.Steps[stepExecution.Name].MessagesDropped.Int64 + stepExecution.MessagesDropped.Int64)
+		}
+
+		if stepExecution.Rows.Valid {
+			r.Steps[stepExecution.Name].Rows.Scan(r.Steps[stepExecution.Name].Rows.Int64 + stepExecution.Rows.Int64)
+		}
+
 		if stepExecution.Code.Valid {
 			r.Steps[stepExecution.Name].Codes[stepExecution.Code.String]++
 		}
+
+		if stepExecution.Protocol.Valid {
+			r.Steps[stepExecution.Name].Protocols[stepExecution.Protocol.String]++
+		}
+
+		if stepExecution.Branch.Valid {
+			branches := r.Steps[stepExecution.Name].Branches
+			if _, ok := branches[stepExecution.Branch.String]; !ok {
+				branches[stepExecution.Branch.String] = &RunStatBranch{}
+				branches[stepExecution.Branch.String].Weight = stepExecution.BranchWeight.Float64
+			}
+
+			branches[stepExecution.Branch.String].CountSelected++
+		}
+
+		for _, stepOperation := range stepExecution.Operations {
+			operations := r.Steps[stepExecution.Name].Operations
+			if _, ok := operations[stepOperation.Name]; !ok {
+				operations[stepOperation.Name] = &RunStatOperation{}
+				operations[stepOperation.Name].DurationMin = stepOperation.Duration
+			}
+
+			operation := operations[stepOperation.Name]
+			operation.Count++
+			operation.DurationMin = utils.MinDuration(operation.DurationMin, stepOperation.Duration)
+			operation.DurationMax = utils.MaxDuration(operation.DurationMax, stepOperation.Duration)
+			operation.durationSum += stepOperation.Duration
+			operation.DurationAvg = time.Duration(math.Round(float64(operation.durationSum) / float64(operation.Count)))
+		}
+	}
+
+	for _, step := range r.Steps {
+		countSelected := int64(0)
+		for _, branch := range step.Branches {
+			countSelected += branch.CountSelected
+		}
+
+		for _, branch := range step.Branches {
+			branch.Share = float64(branch.CountSelected) / float64(countSelected)
+		}
 	}
 
 	for name := range bytesSentCountMap {
@@ -182,6 +474,30 @@ func (r *RunStats) Aggregate() {
 		r.Steps[name].BytesReceivedAvg.Scan(float64(bytesReceivedSumMap[name]) / float64(bytesReceivedCountMap[name]))
 	}
 
+	for name := range bytesReceivedBodyCountMap {
+		r.Steps[name].BytesReceivedBodyAvg.Scan(float64(bytesReceivedBodySumMap[name]) / float64(bytesReceivedBodyCountMap[name]))
+	}
+
+	for name, phases := range phasesMap {
+		r.Steps[name].DurationDNSAvg = phases.dns.avg()
+		r.Steps[name].DurationConnectAvg = phases.connect.avg()
+		r.Steps[name].DurationTLSAvg = phases.tls.avg()
+		r.Steps[name].DurationTTFBAvg = phases.ttfb.avg()
+		r.Steps[name].DurationTransferAvg = phases.transfer.avg()
+		r.Steps[name].DurationRoundTripAvg = phases.rtt.avg()
+		r.Steps[name].DurationFirstEventAvg = phases.firstEvent.avg()
+		r.Steps[name].DurationEndToEndMin, r.Steps[name].DurationEndToEndAvg, r.Steps[name].DurationEndToEndMax = phases.endToEnd.values()
+		r.Steps[name].DurationBetweenEventsMin, r.Steps[name].DurationBetweenEventsAvg, r.Steps[name].DurationBetweenEventsMax = phases.betweenEvents.values()
+	}
+
+	for name := range durationPauseCountMap {
+		durationPauseSum := durationPauseSumMap[name]
+		durationPauseAvg := time.Duration(math.Round(float64(durationPauseSumMap[name]) / float64(durationPauseCountMap[name])))
+
+		r.Steps[name].DurationPauseSum = &durationPauseSum
+		r.Steps[name].DurationPauseAvg = &durationPauseAvg
+	}
+
 	for name := range durationCountMap {
 		r.Steps[name].DurationMin = durationMinMap[name]
 		r.Steps[name].DurationMax = durationMaxMap[name]
@@ -191,10 +507,44 @@ func (r *RunStats) Aggregate() {
 
 func (r *RunStats) Print() {
 	log.Infof("######################## Run stats ########################")
+	if r.Interrupted {
+		log.Infof("Run was interrupted, stats are incomplete")
+	}
 	log.Infof("Steps total:    %d", r.CountStepsTotal)
 	log.Infof("Steps skipped:  %d", r.CountStepsSkipped)
 	log.Infof("Steps succeded: %d", r.CountStepsSucceded)
 	log.Infof("Steps failed:   %d", r.CountStepsFailed)
+	log.Infof("Steps retried:  %d", r.CountStepsRetried)
+	if r.MessagesLost.Valid {
+		log.Infof("Messages lost:  %d", r.MessagesLost.Int64)
+	}
+	log.Infof("Pause total:    %d ms", r.DurationPause.Milliseconds())
+
+	for name, step := range r.Steps {
+		if !step.HasExplicitName || len(step.Branches) == 0 {
+			continue
+		}
+
+		log.Infof("")
+		log.Infof("Branches of step %s:", name)
+
+		for branchName, branch := range step.Branches {
+			log.Infof("   Branch %s:  %d (%.1f %%, expected %.1f %%)", branchName, branch.CountSelected, branch.Share*100, branch.Weight*100)
+		}
+	}
+
+	for name, step := range r.Steps {
+		if !step.HasExplicitName || len(step.Operations) == 0 {
+			continue
+		}
+
+		log.Infof("")
+		log.Infof("Operations of step %s:", name)
+
+		for operationName, operation := range step.Operations {
+			log.Infof("   Operation %s:  %d (avg %d ms, min %d ms, max %d ms)", operationName, operation.Count, operation.DurationAvg.Milliseconds(), operation.DurationMin.Milliseconds(), operation.DurationMax.Milliseconds())
+		}
+	}
 
 	for name, step := range r.Steps {
 		if step.IsGroup || !step.HasExplicitName {
@@ -207,10 +557,68 @@ func (r *RunStats) Print() {
 		log.Infof("   Count skipped:   %d", step.CountSkipped)
 		log.Infof("   Count success:   %d", step.CountSucceded)
 		log.Infof("   Count failed:    %d", step.CountFailed)
+		log.Infof("   Count retried:   %d", step.CountRetried)
 		log.Infof("   Avg duration:  %d ms", step.DurationAvg.Milliseconds())
 		log.Infof("   Max duration:  %d ms", step.DurationMax.Milliseconds())
 		log.Infof("   Min duration:  %d ms", step.DurationMin.Milliseconds())
 
+		if step.DurationPauseAvg != nil {
+			log.Infof("   Avg pause:     %d ms", step.DurationPauseAvg.Milliseconds())
+			log.Infof("   Total pause:   %d ms", step.DurationPauseSum.Milliseconds())
+		}
+
+		if step.DurationTTFBAvg != nil {
+			log.Infof("   Avg dns lookup:     %d ms", durationMilliseconds(step.DurationDNSAvg))
+			log.Infof("   Avg tcp connect:    %d ms", durationMilliseconds(step.DurationConnectAvg))
+			log.Infof("   Avg tls handshake:  %d ms", durationMilliseconds(step.DurationTLSAvg))
+			log.Infof("   Avg ttfb:           %d ms", durationMilliseconds(step.DurationTTFBAvg))
+			log.Infof("   Avg transfer:       %d ms", durationMilliseconds(step.DurationTransferAvg))
+			log.Infof("   Connections new:    %d", step.CountConnectionNew)
+			log.Infof("   Connections reused: %d", step.CountConnectionReused)
+		} else if step.DurationConnectAvg != nil {
+			log.Infof("   Avg connect:        %d ms", durationMilliseconds(step.DurationConnectAvg))
+		}
+
+		if step.DurationRoundTripAvg != nil {
+			log.Infof("   Avg round trip:     %d ms", durationMilliseconds(step.DurationRoundTripAvg))
+		}
+
+		if step.DurationEndToEndAvg != nil {
+			log.Infof("   Avg end-to-end:     %d ms", durationMilliseconds(step.DurationEndToEndAvg))
+			log.Infof("   Min end-to-end:     %d ms", durationMilliseconds(step.DurationEndToEndMin))
+			log.Infof("   Max end-to-end:     %d ms", durationMilliseconds(step.DurationEndToEndMax))
+		}
+
+		if step.DurationFirstEventAvg != nil {
+			log.Infof("   Avg first event:    %d ms", durationMilliseconds(step.DurationFirstEventAvg))
+		}
+
+		if step.DurationBetweenEventsAvg != nil {
+			log.Infof("   Avg between events: %d ms", durationMilliseconds(step.DurationBetweenEventsAvg))
+			log.Infof("   Min between events: %d ms", durationMilliseconds(step.DurationBetweenEventsMin))
+			log.Infof("   Max between events: %d ms", durationMilliseconds(step.DurationBetweenEventsMax))
+		}
+
+		if step.MessagesSent.Valid {
+			log.Infof("   Messages sent:      %d", step.MessagesSent.Int64)
+		}
+
+		if step.MessagesReceived.Valid {
+			log.Infof("   Messages received:  %d", step.MessagesReceived.Int64)
+		}
+
+		if step.MessagesDuplicate.Valid {
+			log.Infof("   Messages duplicate: %d", step.MessagesDuplicate.Int64)
+		}
+
+		if step.MessagesDropped.Valid {
+			log.Infof("   Messages dropped:   %d", step.MessagesDropped.Int64)
+		}
+
+		if step.Rows.Valid {
+			log.Infof("   Rows:               %d", step.Rows.Int64)
+		}
+
 		if step.BytesSentAvg.Valid {
 			log.Infof("   Avg bytes sent:  %.0f b", step.BytesSentAvg.Float64)
 			log.Infof("   Max bytes sent:  %d b", step.BytesSentMax.Int64)
@@ -223,12 +631,28 @@ func (r *RunStats) Print() {
 			log.Infof("   Min bytes received:  %d b", step.BytesReceivedMin.Int64)
 		}
 
+		if step.BytesReceivedBodyAvg.Valid {
+			log.Infof("   Avg body bytes received:  %.0f b", step.BytesReceivedBodyAvg.Float64)
+		}
+
 		for code, count := range step.Codes {
 			log.Infof("   Code %s:        %d", code, count)
 		}
+
+		for protocol, count := range step.Protocols {
+			log.Infof("   Protocol %s:    %d", protocol, count)
+		}
 	}
 }
 
+func durationMilliseconds(duration *time.Duration) int64 {
+	if duration == nil {
+		return 0
+	}
+
+	return duration.Milliseconds()
+}
+
 func NewRunStats() *RunStats {
 	return &RunStats{}
 }
@@ -0,0 +1,19 @@
+package utils
+
+import (
+	"context"
+	"time"
+)
+
+// SleepContext pauses for the given duration or until the context is done
+func SleepContext(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}